@@ -0,0 +1,441 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"gopkg.in/yaml.v3"
+)
+
+// define a configuração do ciclo de vida do bucket lida do arquivo
+type lifecycleConfig struct {
+	Rules []lifecycleRule `json:"rules" yaml:"rules"`
+}
+
+// define uma regra do ciclo de vida
+type lifecycleRule struct {
+	// identificação da regra
+	ID string `json:"id" yaml:"id"`
+	// prefixo dos objetos afetados pela regra
+	Prefix string `json:"prefix" yaml:"prefix"`
+	// tags que os objetos afetados devem possuir
+	Tags map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// limites de tamanho dos objetos afetados
+	ObjectSizeGreaterThan int64 `json:"object_size_greater_than,omitempty" yaml:"object_size_greater_than,omitempty"`
+	ObjectSizeLessThan    int64 `json:"object_size_less_than,omitempty" yaml:"object_size_less_than,omitempty"`
+	// indica se a regra está desabilitada
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	// dias após a criação para remover os objetos
+	ExpirationDays int32 `json:"expiration_days,omitempty" yaml:"expiration_days,omitempty"`
+	// dias após deixarem de ser a versão atual para remover os objetos
+	NoncurrentExpirationDays int32 `json:"noncurrent_expiration_days,omitempty" yaml:"noncurrent_expiration_days,omitempty"`
+	// dias após o início para cancelar os envios multipart incompletos
+	AbortIncompleteMultipartDays int32 `json:"abort_incomplete_multipart_days,omitempty" yaml:"abort_incomplete_multipart_days,omitempty"`
+	// mudanças da classe de armazenamento
+	Transitions []lifecycleTransition `json:"transitions,omitempty" yaml:"transitions,omitempty"`
+}
+
+// define a mudança da classe de armazenamento após os dias informados
+type lifecycleTransition struct {
+	Days         int32  `json:"days" yaml:"days"`
+	StorageClass string `json:"storage_class" yaml:"storage_class"`
+}
+
+// processa o comando de administração dos buckets
+func processBucket(args []string) {
+	// define o help do comando
+	help := "Usage:\n"
+	help += " s3 bucket create -?\n"
+	help += " s3 bucket delete -?\n"
+	help += " s3 bucket info -?\n"
+	help += " s3 bucket versioning -?\n"
+	help += " s3 bucket lifecycle -?\n"
+	if len(args) == 0 {
+		fmt.Print(help)
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "create":
+		processBucketCreate(args[1:])
+	case "delete":
+		processBucketDelete(args[1:])
+	case "info":
+		processBucketInfo(args[1:])
+	case "versioning":
+		processBucketVersioning(args[1:])
+	case "lifecycle":
+		processBucketLifecycle(args[1:])
+	default:
+		fmt.Print(help)
+		os.Exit(1)
+	}
+}
+
+// processa os parametros comuns dos comandos de administração do bucket
+// e inicializa o serviço da aws
+func parseBucketCommand(cmd *flag.FlagSet, pBucketFlags *bucketFlags, args []string) {
+	// processa os parametros
+	err := cmd.Parse(args)
+	if err != nil || len(args) == 0 {
+		cmd.Usage()
+		os.Exit(1)
+	}
+	// aplica os parametros de acesso ao bucket
+	pBucketFlags.apply()
+	// valida se há parametros suficientes
+	if myConfig.Bucket == "" {
+		cmd.Usage()
+		os.Exit(1)
+	}
+	// inicializa o serviço da aws
+	err = connect(*pBucketFlags.Role)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// loga o endpoint e o bucket que será usado
+	logEndpoint()
+}
+
+// processa o comando de criação do bucket
+func processBucketCreate(args []string) {
+	cmd := flag.NewFlagSet("bucket create", flag.ExitOnError)
+	pBucketFlags := addBucketFlags(cmd)
+	pVersioning := cmd.Bool("versioning", false, "enable versioning of the bucket")
+	parseBucketCommand(cmd, pBucketFlags, args)
+	// a região padrão não aceita ser informada na criação
+	input := &s3.CreateBucketInput{
+		Bucket: aws.String(myConfig.Bucket),
+	}
+	if s3region != "" && s3region != "us-east-1" {
+		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(s3region),
+		}
+	}
+	_, err := s3client.CreateBucket(context.TODO(), input)
+	if err != nil {
+		log.Fatalf("unable to create bucket {%s}, %s", myConfig.Bucket, err)
+	}
+	log.Printf("bucket {%s} created successfully", myConfig.Bucket)
+	if *pVersioning {
+		err = setBucketVersioning(true)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// processa o comando de remoção do bucket
+func processBucketDelete(args []string) {
+	cmd := flag.NewFlagSet("bucket delete", flag.ExitOnError)
+	pBucketFlags := addBucketFlags(cmd)
+	parseBucketCommand(cmd, pBucketFlags, args)
+	// o bucket deve estar vazio para ser removido
+	_, err := s3client.DeleteBucket(context.TODO(), &s3.DeleteBucketInput{
+		Bucket: aws.String(myConfig.Bucket),
+	})
+	if err != nil {
+		log.Fatalf("unable to delete bucket {%s}, %s", myConfig.Bucket, err)
+	}
+	log.Printf("bucket {%s} deleted successfully", myConfig.Bucket)
+}
+
+// processa o comando de exibição das configurações do bucket
+func processBucketInfo(args []string) {
+	cmd := flag.NewFlagSet("bucket info", flag.ExitOnError)
+	pBucketFlags := addBucketFlags(cmd)
+	parseBucketCommand(cmd, pBucketFlags, args)
+	// identifica a região
+	location, err := s3client.GetBucketLocation(context.TODO(), &s3.GetBucketLocationInput{
+		Bucket: aws.String(myConfig.Bucket),
+	})
+	if err != nil {
+		log.Fatalf("unable to read location of bucket {%s}, %s", myConfig.Bucket, err)
+	}
+	region := string(location.LocationConstraint)
+	if region == "" {
+		region = "us-east-1"
+	}
+	// identifica o versionamento
+	versioning, err := s3client.GetBucketVersioning(context.TODO(), &s3.GetBucketVersioningInput{
+		Bucket: aws.String(myConfig.Bucket),
+	})
+	if err != nil {
+		log.Fatalf("unable to read versioning of bucket {%s}, %s", myConfig.Bucket, err)
+	}
+	status := string(versioning.Status)
+	if status == "" {
+		status = "Disabled"
+	}
+	// identifica o ciclo de vida
+	rules, err := bucketLifecycle()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%-12s %s\n", "bucket:", myConfig.Bucket)
+	fmt.Printf("%-12s %s\n", "region:", region)
+	fmt.Printf("%-12s %s\n", "versioning:", status)
+	fmt.Printf("%-12s %d rules\n", "lifecycle:", len(rules.Rules))
+	if len(rules.Rules) > 0 {
+		data, _ := json.MarshalIndent(rules, "", "  ")
+		fmt.Println(string(data))
+	}
+}
+
+// processa o comando de versionamento do bucket
+func processBucketVersioning(args []string) {
+	cmd := flag.NewFlagSet("bucket versioning", flag.ExitOnError)
+	pBucketFlags := addBucketFlags(cmd)
+	pEnable := cmd.Bool("enable", false, "enable versioning of the bucket")
+	pDisable := cmd.Bool("disable", false, "suspend versioning of the bucket")
+	parseBucketCommand(cmd, pBucketFlags, args)
+	if *pEnable == *pDisable {
+		log.Fatalf("one of enable or disable must be provided")
+	}
+	err := setBucketVersioning(*pEnable)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// processa o comando de ciclo de vida do bucket
+func processBucketLifecycle(args []string) {
+	cmd := flag.NewFlagSet("bucket lifecycle", flag.ExitOnError)
+	pBucketFlags := addBucketFlags(cmd)
+	pFile := cmd.String("file", "", "json or yaml file with the lifecycle rules that will replace the rules of the bucket")
+	pRemove := cmd.Bool("rm", false, "remove all lifecycle rules of the bucket")
+	parseBucketCommand(cmd, pBucketFlags, args)
+	switch {
+	case *pRemove:
+		_, err := s3client.DeleteBucketLifecycle(context.TODO(), &s3.DeleteBucketLifecycleInput{
+			Bucket: aws.String(myConfig.Bucket),
+		})
+		if err != nil {
+			log.Fatalf("unable to remove lifecycle of bucket {%s}, %s", myConfig.Bucket, err)
+		}
+		log.Printf("lifecycle of bucket {%s} removed successfully", myConfig.Bucket)
+	case *pFile != "":
+		rules, err := readLifecycle(*pFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		input, err := rules.bucketConfiguration()
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, err = s3client.PutBucketLifecycleConfiguration(context.TODO(), &s3.PutBucketLifecycleConfigurationInput{
+			Bucket:                 aws.String(myConfig.Bucket),
+			LifecycleConfiguration: input,
+		})
+		if err != nil {
+			log.Fatalf("unable to apply lifecycle of bucket {%s}, %s", myConfig.Bucket, err)
+		}
+		log.Printf("lifecycle with %d rules applied to bucket {%s}", len(rules.Rules), myConfig.Bucket)
+	default:
+		rules, err := bucketLifecycle()
+		if err != nil {
+			log.Fatal(err)
+		}
+		data, _ := json.MarshalIndent(rules, "", "  ")
+		fmt.Println(string(data))
+	}
+}
+
+// habilita ou suspende o versionamento do bucket
+func setBucketVersioning(enable bool) error {
+	status := types.BucketVersioningStatusSuspended
+	if enable {
+		status = types.BucketVersioningStatusEnabled
+	}
+	_, err := s3client.PutBucketVersioning(context.TODO(), &s3.PutBucketVersioningInput{
+		Bucket: aws.String(myConfig.Bucket),
+		VersioningConfiguration: &types.VersioningConfiguration{
+			Status: status,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to change versioning of bucket {%s}, %s", myConfig.Bucket, err)
+	}
+	log.Printf("versioning of bucket {%s} changed to {%s}", myConfig.Bucket, status)
+	return nil
+}
+
+// lê as regras do ciclo de vida do bucket
+func bucketLifecycle() (*lifecycleConfig, error) {
+	output, err := s3client.GetBucketLifecycleConfiguration(context.TODO(), &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(myConfig.Bucket),
+	})
+	if err != nil {
+		// o bucket sem ciclo de vida retorna erro
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchLifecycleConfiguration" {
+			return &lifecycleConfig{}, nil
+		}
+		return nil, fmt.Errorf("unable to read lifecycle of bucket {%s}, %s", myConfig.Bucket, err)
+	}
+	return newLifecycleConfig(output.Rules)
+}
+
+// lê as regras do ciclo de vida do arquivo json ou yaml
+func readLifecycle(file string) (*lifecycleConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read lifecycle file {%s}, %s", file, err)
+	}
+	rules := &lifecycleConfig{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, rules)
+	default:
+		err = json.Unmarshal(data, rules)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse lifecycle file {%s}, %s", file, err)
+	}
+	return rules, nil
+}
+
+// converte as regras do ciclo de vida para a configuração do bucket
+func (p *lifecycleConfig) bucketConfiguration() (*types.BucketLifecycleConfiguration, error) {
+	if len(p.Rules) == 0 {
+		return nil, fmt.Errorf("lifecycle has no rules")
+	}
+	config := &types.BucketLifecycleConfiguration{}
+	for k, v := range p.Rules {
+		if v.ID == "" {
+			return nil, fmt.Errorf("[%d] lifecycle rule id not provided", k)
+		}
+		if v.ExpirationDays == 0 && v.NoncurrentExpirationDays == 0 && v.AbortIncompleteMultipartDays == 0 && len(v.Transitions) == 0 {
+			return nil, fmt.Errorf("[%d] lifecycle rule {%s} has no action", k, v.ID)
+		}
+		rule := types.LifecycleRule{
+			ID:     aws.String(v.ID),
+			Status: types.ExpirationStatusEnabled,
+			Filter: v.filter(),
+		}
+		if v.Disabled {
+			rule.Status = types.ExpirationStatusDisabled
+		}
+		if v.ExpirationDays > 0 {
+			rule.Expiration = &types.LifecycleExpiration{Days: v.ExpirationDays}
+		}
+		if v.NoncurrentExpirationDays > 0 {
+			rule.NoncurrentVersionExpiration = &types.NoncurrentVersionExpiration{NoncurrentDays: v.NoncurrentExpirationDays}
+		}
+		if v.AbortIncompleteMultipartDays > 0 {
+			rule.AbortIncompleteMultipartUpload = &types.AbortIncompleteMultipartUpload{DaysAfterInitiation: v.AbortIncompleteMultipartDays}
+		}
+		for _, t := range v.Transitions {
+			rule.Transitions = append(rule.Transitions, types.Transition{
+				Days:         t.Days,
+				StorageClass: types.TransitionStorageClass(strings.ToUpper(t.StorageClass)),
+			})
+		}
+		config.Rules = append(config.Rules, rule)
+	}
+	return config, nil
+}
+
+// define o filtro da regra, apenas o prefixo usa o filtro de prefixo e
+// mais de uma condição usa o filtro com todas as condições
+func (p *lifecycleRule) filter() types.LifecycleRuleFilter {
+	conditions := len(p.Tags)
+	if p.ObjectSizeGreaterThan > 0 {
+		conditions++
+	}
+	if p.ObjectSizeLessThan > 0 {
+		conditions++
+	}
+	if conditions == 0 {
+		return &types.LifecycleRuleFilterMemberPrefix{Value: p.Prefix}
+	}
+	// as tags são ordenadas para gerar sempre a mesma configuração
+	keys := make([]string, 0, len(p.Tags))
+	for k := range p.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tags := make([]types.Tag, len(keys))
+	for k, v := range keys {
+		tags[k] = types.Tag{Key: aws.String(v), Value: aws.String(p.Tags[v])}
+	}
+	if p.Prefix == "" && conditions == 1 {
+		switch {
+		case len(tags) == 1:
+			return &types.LifecycleRuleFilterMemberTag{Value: tags[0]}
+		case p.ObjectSizeGreaterThan > 0:
+			return &types.LifecycleRuleFilterMemberObjectSizeGreaterThan{Value: p.ObjectSizeGreaterThan}
+		default:
+			return &types.LifecycleRuleFilterMemberObjectSizeLessThan{Value: p.ObjectSizeLessThan}
+		}
+	}
+	and := types.LifecycleRuleAndOperator{
+		Tags:                  tags,
+		ObjectSizeGreaterThan: p.ObjectSizeGreaterThan,
+		ObjectSizeLessThan:    p.ObjectSizeLessThan,
+	}
+	if p.Prefix != "" {
+		and.Prefix = aws.String(p.Prefix)
+	}
+	return &types.LifecycleRuleFilterMemberAnd{Value: and}
+}
+
+// converte a configuração do bucket para as regras do ciclo de vida
+func newLifecycleConfig(rules []types.LifecycleRule) (*lifecycleConfig, error) {
+	config := &lifecycleConfig{}
+	for _, v := range rules {
+		rule := lifecycleRule{
+			ID:       aws.ToString(v.ID),
+			Prefix:   aws.ToString(v.Prefix),
+			Disabled: v.Status == types.ExpirationStatusDisabled,
+		}
+		switch filter := v.Filter.(type) {
+		case nil:
+		case *types.LifecycleRuleFilterMemberPrefix:
+			rule.Prefix = filter.Value
+		case *types.LifecycleRuleFilterMemberTag:
+			rule.Tags = map[string]string{aws.ToString(filter.Value.Key): aws.ToString(filter.Value.Value)}
+		case *types.LifecycleRuleFilterMemberObjectSizeGreaterThan:
+			rule.ObjectSizeGreaterThan = filter.Value
+		case *types.LifecycleRuleFilterMemberObjectSizeLessThan:
+			rule.ObjectSizeLessThan = filter.Value
+		case *types.LifecycleRuleFilterMemberAnd:
+			rule.Prefix = aws.ToString(filter.Value.Prefix)
+			rule.ObjectSizeGreaterThan = filter.Value.ObjectSizeGreaterThan
+			rule.ObjectSizeLessThan = filter.Value.ObjectSizeLessThan
+			for _, t := range filter.Value.Tags {
+				if rule.Tags == nil {
+					rule.Tags = make(map[string]string)
+				}
+				rule.Tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+			}
+		default:
+			return nil, fmt.Errorf("lifecycle rule {%s} of bucket {%s} has an unsupported filter", rule.ID, myConfig.Bucket)
+		}
+		if v.Expiration != nil {
+			rule.ExpirationDays = v.Expiration.Days
+		}
+		if v.NoncurrentVersionExpiration != nil {
+			rule.NoncurrentExpirationDays = v.NoncurrentVersionExpiration.NoncurrentDays
+		}
+		if v.AbortIncompleteMultipartUpload != nil {
+			rule.AbortIncompleteMultipartDays = v.AbortIncompleteMultipartUpload.DaysAfterInitiation
+		}
+		for _, t := range v.Transitions {
+			rule.Transitions = append(rule.Transitions, lifecycleTransition{Days: t.Days, StorageClass: string(t.StorageClass)})
+		}
+		config.Rules = append(config.Rules, rule)
+	}
+	return config, nil
+}
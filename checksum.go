@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Define os algoritmos para verificação da integridade dos arquivos
+const (
+	ChecksumMD5    = "md5"
+	ChecksumSHA256 = "sha256"
+	ChecksumCRC32C = "crc32c"
+)
+
+// define o prefixo dos metadados onde é gravado o checksum do arquivo
+const checksumMetadata = "checksum-"
+
+// cria o hash para o algoritmo informado
+func newChecksum(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case ChecksumMD5:
+		return md5.New(), nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	}
+	return nil, fmt.Errorf("checksum algorithm {%s} is invalid", algorithm)
+}
+
+// calcula o ETag que o bucket gera para o conteúdo gravado, quando o
+// envio for multipart o ETag é o md5 dos md5 de cada parte seguido da
+// quantidade de partes
+type etagWriter struct {
+	partSize int64
+	part     hash.Hash
+	written  int64
+	parts    []byte
+	count    int
+}
+
+// cria o calculador de ETag para o tamanho de parte informado
+func newETagWriter(partSize int64) *etagWriter {
+	return &etagWriter{partSize: partSize, part: md5.New()}
+}
+
+// grava o conteúdo separando as partes
+func (p *etagWriter) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		chunk := b
+		if int64(len(chunk)) > p.partSize-p.written {
+			chunk = chunk[:p.partSize-p.written]
+		}
+		p.part.Write(chunk)
+		p.written += int64(len(chunk))
+		n += len(chunk)
+		b = b[len(chunk):]
+		if p.written == p.partSize {
+			p.parts = p.part.Sum(p.parts)
+			p.count++
+			p.part.Reset()
+			p.written = 0
+		}
+	}
+	return n, nil
+}
+
+// retorna o ETag do conteúdo gravado
+func (p *etagWriter) ETag() string {
+	parts, count := p.parts, p.count
+	if p.written > 0 || count == 0 {
+		parts = p.part.Sum(parts)
+		count++
+	}
+	if count == 1 {
+		return hex.EncodeToString(parts)
+	}
+	sum := md5.Sum(parts)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), count)
+}
+
+// calcula o checksum e o ETag do conteúdo enquanto é transferido, para
+// que o conteúdo seja lido uma única vez
+type contentHash struct {
+	algorithm string
+	sum       hash.Hash
+	etag      *etagWriter
+}
+
+// cria o calculador do checksum e do ETag para o tamanho de parte informado
+func newContentHash(algorithm string, partSize int64) (*contentHash, error) {
+	sum, err := newChecksum(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &contentHash{algorithm: algorithm, sum: sum, etag: newETagWriter(partSize)}, nil
+}
+
+// grava o conteúdo no checksum e no ETag
+func (p *contentHash) Write(b []byte) (int, error) {
+	p.sum.Write(b)
+	return p.etag.Write(b)
+}
+
+// retorna o checksum do conteúdo gravado
+func (p *contentHash) Sum() string {
+	return hex.EncodeToString(p.sum.Sum(nil))
+}
+
+// retorna o ETag do conteúdo gravado
+func (p *contentHash) ETag() string {
+	return p.etag.ETag()
+}
+
+// grava as partes recebidas no arquivo calculando o checksum, as partes
+// devem ser gravadas em sequência e os bytes gravados novamente em uma
+// nova tentativa não são contabilizados duas vezes
+type hashWriterAt struct {
+	w      io.WriterAt
+	hash   io.Writer
+	offset int64
+}
+
+// grava a parte no arquivo e no checksum
+func (p *hashWriterAt) WriteAt(b []byte, off int64) (int, error) {
+	if off > p.offset {
+		return 0, fmt.Errorf("unable to compute checksum, write at offset %d before offset %d", off, p.offset)
+	}
+	n, err := p.w.WriteAt(b, off)
+	if end := off + int64(n); end > p.offset {
+		p.hash.Write(b[p.offset-off : n])
+		p.offset = end
+	}
+	return n, err
+}
+
+// lê uma parte do arquivo calculando o md5 do conteúdo enviado, quando o
+// envio é repetido a partir do início o md5 é reiniciado
+type partReader struct {
+	r      *io.SectionReader
+	hash   hash.Hash
+	pos    int64
+	hashed int64
+}
+
+// cria o leitor da parte do arquivo
+func newPartReader(r io.ReaderAt, offset int64, length int64) *partReader {
+	return &partReader{r: io.NewSectionReader(r, offset, length), hash: md5.New()}
+}
+
+// lê o conteúdo da parte
+func (p *partReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if p.pos == p.hashed {
+		p.hash.Write(b[:n])
+		p.hashed += int64(n)
+	}
+	p.pos += int64(n)
+	return n, err
+}
+
+// posiciona a leitura da parte
+func (p *partReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := p.r.Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+	p.pos = pos
+	if pos == 0 {
+		p.hash.Reset()
+		p.hashed = 0
+	}
+	return pos, nil
+}
+
+// retorna o md5 da parte, vazio se a parte não foi lida por completo
+func (p *partReader) MD5() string {
+	if p.hashed != p.r.Size() {
+		return ""
+	}
+	return hex.EncodeToString(p.hash.Sum(nil))
+}
+
+// calcula o ETag do envio multipart a partir do ETag de cada parte
+func multipartETag(etags []string) (string, error) {
+	var parts []byte
+	for _, v := range etags {
+		sum, err := hex.DecodeString(strings.Trim(v, `"`))
+		if err != nil {
+			return "", fmt.Errorf("part etag {%s} is invalid, %s", v, err)
+		}
+		parts = append(parts, sum...)
+	}
+	sum := md5.Sum(parts)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(etags)), nil
+}
+
+// calcula o ETag que o bucket gera para o arquivo
+func fileETag(file string, partSize int64) (string, error) {
+	_, etag, err := fileChecksum(file, "", partSize)
+	return etag, err
+}
+
+// calcula o checksum do arquivo com o algoritmo informado e o ETag que
+// o bucket gera para o arquivo em uma única leitura
+func fileChecksum(file string, algorithm string, partSize int64) (sum string, etag string, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to open file {%s}, %s", file, err)
+	}
+	defer f.Close()
+	etagHash := newETagWriter(partSize)
+	var w io.Writer = etagHash
+	var h hash.Hash
+	if algorithm != "" {
+		h, err = newChecksum(algorithm)
+		if err != nil {
+			return "", "", err
+		}
+		w = io.MultiWriter(h, etagHash)
+	}
+	_, err = io.Copy(w, f)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read file {%s}, %s", file, err)
+	}
+	if h != nil {
+		sum = hex.EncodeToString(h.Sum(nil))
+	}
+	return sum, etagHash.ETag(), nil
+}
+
+// verifica se o ETag do objeto pode ser comparado com o md5 do conteúdo,
+// objetos criptografados com chave do KMS ou do cliente possuem ETag
+// que não é baseado no md5
+func comparableETag(sse types.ServerSideEncryption, customerKey *string) bool {
+	return sse != types.ServerSideEncryptionAwsKms && customerKey == nil
+}
+
+// verifica se o objeto gravado no bucket corresponde ao arquivo local
+func verifyUpload(key string, size int64, sum string, etag string, algorithm string) error {
+	head, err := s3client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(myConfig.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to read properties of object, %s", err)
+	}
+	if head.ContentLength != size {
+		return fmt.Errorf("checksum mismatch, object size %d differs from file size %d", head.ContentLength, size)
+	}
+	// compara o ETag quando for baseado no md5 do conteúdo
+	verified := false
+	remote := strings.Trim(aws.ToString(head.ETag), `"`)
+	if comparableETag(head.ServerSideEncryption, head.SSECustomerKeyMD5) {
+		if remote != etag {
+			return fmt.Errorf("checksum mismatch, object etag {%s} differs from file etag {%s}", remote, etag)
+		}
+		verified = true
+	}
+	// compara o checksum gravado nos metadados do objeto
+	if stored, ok := head.Metadata[checksumMetadata+algorithm]; ok && sum != "" {
+		if stored != sum {
+			return fmt.Errorf("checksum mismatch, object %s {%s} differs from file %s {%s}", algorithm, stored, algorithm, sum)
+		}
+		verified = true
+	}
+	if !verified {
+		log.Printf("etag of object {%s} is not comparable, only size was verified", key)
+	}
+	return nil
+}
+
+// grava o checksum nos metadados do objeto copiando o objeto sobre ele
+// mesmo no próprio serviço, pois o checksum só é conhecido após o envio
+func storeChecksum(key string, metaData map[string]string, algorithm string, sum string) error {
+	_, _, err := copyObject(key, myConfig.Bucket, key, withChecksum(metaData, algorithm, sum))
+	if err != nil {
+		return fmt.Errorf("unable to store %s checksum in metadata of object, %s", algorithm, err)
+	}
+	return nil
+}
+
+// retorna uma cópia dos metadados incluindo o checksum do arquivo
+func withChecksum(metaData map[string]string, algorithm string, sum string) map[string]string {
+	result := make(map[string]string, len(metaData)+1)
+	for k, v := range metaData {
+		result[k] = v
+	}
+	result[checksumMetadata+algorithm] = sum
+	return result
+}
+
+// verifica se o conteúdo recebido corresponde ao objeto do bucket, o
+// checksum e o ETag são calculados enquanto o conteúdo é gravado
+func verifyDownload(file string, n int64, head *s3.HeadObjectOutput, h *contentHash) error {
+	if n != head.ContentLength {
+		return fmt.Errorf("checksum mismatch, received %d bytes of %d", n, head.ContentLength)
+	}
+	return compareDownload(file, h.Sum(), h.ETag(), head, h.algorithm)
+}
+
+// compara o checksum e o ETag do conteúdo recebido com o objeto do bucket
+func compareDownload(file string, sum string, etag string, head *s3.HeadObjectOutput, algorithm string) error {
+	// compara o ETag quando for baseado no md5 do conteúdo
+	remote := strings.Trim(aws.ToString(head.ETag), `"`)
+	verified := false
+	if comparableETag(head.ServerSideEncryption, head.SSECustomerKeyMD5) {
+		if remote == etag {
+			verified = true
+		} else if !strings.Contains(remote, "-") {
+			return fmt.Errorf("checksum mismatch, file etag {%s} differs from object etag {%s}", etag, remote)
+		} else {
+			// o tamanho das partes do envio multipart não é conhecido e o
+			// ETag calculado pode divergir mesmo com o conteúdo correto
+			log.Printf("etag {%s} of file {%s} differs from multipart object etag {%s}, part size is unknown", etag, file, remote)
+		}
+	}
+	// compara o checksum gravado nos metadados do objeto
+	if stored, ok := head.Metadata[checksumMetadata+algorithm]; ok {
+		if stored != sum {
+			return fmt.Errorf("checksum mismatch, file %s {%s} differs from object %s {%s}", algorithm, sum, algorithm, stored)
+		}
+		verified = true
+	}
+	if !verified {
+		log.Printf("object has no %s checksum stored and etag is not comparable, only size of file {%s} was verified", algorithm, file)
+	}
+	return nil
+}
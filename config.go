@@ -25,6 +25,8 @@ type Config struct {
 	VaultAddress    string            `json:"vault_address,omitempty"`
 	VaultEnginePath string            `json:"vault_token_engine_path,omitempty"`
 	LocalFolder     string            `json:"local_folder,omitempty"`
+	// pasta dos checkpoints para retomar transferências interrompidas
+	CheckpointFolder string `json:"local_checkpoint_folder,omitempty"`
 	// autenticação basica
 	AccessKey   string `json:"bucket_access_key,omitempty"`
 	SecretKey   string `json:"bucket_secret_key,omitempty"`
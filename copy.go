@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// define o tamanho máximo de um objeto copiado com uma única requisição
+const maxCopySize = 5 * 1024 * 1024 * 1024
+
+// processa o comando de cópia de arquivos entre chaves e buckets
+func processCopy(args []string) {
+	processCopyMove("cp", args, false)
+}
+
+// processa o comando de movimentação de arquivos entre chaves e buckets
+func processMove(args []string) {
+	processCopyMove("mv", args, true)
+}
+
+// processa os comandos de cópia e movimentação, na movimentação o arquivo
+// de origem é removido após a confirmação da cópia
+func processCopyMove(command string, args []string, move bool) {
+	// identifica os flags informados
+	cmdCopy := flag.NewFlagSet(command, flag.ExitOnError)
+	// define os parametros para sobrescrever o padrão configurado
+	pBucketFlags := addBucketFlags(cmdCopy)
+	// define os parametros para utilização específicos para este método
+	pFilter := cmdCopy.String("f", "", "filter to select files")
+	pRename := cmdCopy.String("c", "", fmt.Sprintf("change the name of target file\n%s", renameVars))
+	pMetaData := cmdCopy.String("m", "", "metadata that will replace the metadata of the copied files (sintax key1=value1;key2=value2...)")
+	pErrorNoFiles := cmdCopy.Bool("enf", false, "terminate with exit code 1 if no files found")
+	pWorkers := cmdCopy.Int("j", 1, "number of files copied in parallel")
+	pLayout := cmdCopy.String("layout", LayoutFlat, "layout of copied files in target prefix (flat: all files in the same prefix, tree: recreate the key path below bucket prefix)")
+	pTargetBucket := cmdCopy.String("db", "", "target bucket name (default is the source bucket)")
+	pTargetPrefix := cmdCopy.String("dp", "", "target bucket prefix (sub folder)")
+	// processa os parametros
+	err := cmdCopy.Parse(args)
+	if err != nil || len(args) == 0 {
+		cmdCopy.Usage()
+		os.Exit(1)
+	}
+	// aplica os parametros de acesso ao bucket
+	pBucketFlags.apply()
+	// define os metadados que substituirão os metadados da origem
+	var metaData map[string]string
+	if *pMetaData != "" {
+		metaData, err = parseMetadata(*pMetaData)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	// valida o filtro
+	if *pFilter == "" {
+		log.Fatalf("file name filter not provided")
+	}
+	// valida o rename
+	if *pRename == "" {
+		*pRename = "#FN#FE"
+	}
+	// valida a quantidade de cópias em paralelo
+	if *pWorkers < 1 {
+		log.Fatalf("number of parallel copies {%d} is invalid", *pWorkers)
+	}
+	// valida a forma de gravação dos arquivos no prefixo de destino
+	*pLayout = strings.ToLower(*pLayout)
+	if *pLayout != LayoutFlat && *pLayout != LayoutTree {
+		log.Fatalf("layout {%s} is invalid", *pLayout)
+	}
+	// valida se há parametros suficientes
+	if myConfig.Bucket == "" {
+		cmdCopy.Usage()
+		os.Exit(1)
+	}
+	// o bucket de destino padrão é o próprio bucket de origem
+	if *pTargetBucket == "" {
+		*pTargetBucket = myConfig.Bucket
+	}
+	// inicializa o serviço da aws
+	err = connect(*pBucketFlags.Role)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// executa as cópias
+	err = copyFiles(*pFilter, *pBucketFlags.Prefix, *pTargetBucket, normalizePrefix(*pTargetPrefix), *pRename, metaData, *pErrorNoFiles, *pWorkers, *pLayout, move)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Copia no próprio serviço todos os arquivos que atendem ao filtro
+// especificado para o bucket e prefixo de destino, na movimentação os
+// arquivos de origem são removidos após a confirmação da cópia
+func copyFiles(filter string, prefix string, targetBucket string, targetPrefix string, rename string, metaData map[string]string, errornofiles bool, workers int, layout string, move bool) error {
+	operation := "copy"
+	if move {
+		operation = "move"
+	}
+	// loga o endpoint e o bucket que será usado
+	logEndpoint()
+	// ajusta os campos traduzindo as variaveis se utilizadas
+	prefix = parseName("", prefix)
+	filter = parseName("", filter)
+	targetPrefix = parseName("", targetPrefix)
+	// seleciona os objetos do bucket que atendem ao filtro
+	matches, err := selectObjects(filter, prefix)
+	if err != nil {
+		return err
+	}
+	// verifica se foi selecionado algum arquivo
+	if len(matches) == 0 {
+		log.Printf("no files found in bucket {%s} with filter {%s}", myConfig.Bucket, filter)
+		if errornofiles {
+			os.Exit(1)
+		}
+		return nil
+	}
+	// define o nome de destino e lista os arquivos
+	targets, err := copyTargets(matches, prefix, targetBucket, targetPrefix, rename, layout, metaData != nil, move)
+	if err != nil {
+		return err
+	}
+	for k, v := range matches {
+		log.Printf("[%d] selected to %s: %s => %s/%s", k, operation, *v.Key, targetBucket, targets[k])
+	}
+	// define os resultados de cada cópia
+	results := make([]transferResult, len(matches))
+	// realiza as cópias
+	started := time.Now()
+	runWorkers(len(matches), workers, func(k int) {
+		key := *matches[k].Key
+		results[k].Name = key
+		// captura o horário de início da cópia
+		start := time.Now()
+		log.Printf("[%d] starting %s of file {%s}...", k, operation, key)
+		n, etag, err := copyObject(key, targetBucket, targets[k], metaData)
+		if err != nil {
+			results[k].Err = err
+			log.Printf("[%d] failed to copy file {%s}, %s", k, key, err)
+			return
+		}
+		// remove a origem somente após confirmar a cópia no destino
+		if move {
+			err = confirmCopy(targetBucket, targets[k], n, etag)
+			if err != nil {
+				results[k].Err = err
+				log.Printf("[%d] failed to confirm copy of file {%s}, source not removed, %s", k, key, err)
+				return
+			}
+			_, err = s3client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+				Bucket: aws.String(myConfig.Bucket),
+				Key:    aws.String(key),
+			})
+			if err != nil {
+				results[k].Err = err
+				log.Printf("[%d] failed to remove file {%s} after copy, %s", k, key, err)
+				return
+			}
+		}
+		results[k].Size = n
+		elapsed := time.Since(start).Seconds()
+		log.Printf("[%d] %s completed, size: %d elapsed: %.2fs rate: %.2fMB/s target: %s/%s", k, operation, n, elapsed, transferRate(n, elapsed), targetBucket, targets[k])
+	})
+	// exibe o resumo das cópias
+	failed := logSummary(operation, results, time.Since(started).Seconds())
+	if failed > 0 {
+		return fmt.Errorf("failed to %s %d of %d files", operation, failed, len(results))
+	}
+	return nil
+}
+
+// define a chave de destino de cada objeto, o destino não pode ser a
+// própria origem, exceto na cópia que substitui os metadados. Na cópia é
+// alertado quando mais de um objeto gerar o mesmo destino e na
+// movimentação é retornado erro pois as origens são removidas
+func copyTargets(matches []types.Object, prefix string, targetBucket string, targetPrefix string, rename string, layout string, replace bool, move bool) ([]string, error) {
+	targets := make([]string, len(matches))
+	sources := make(map[string]int)
+	for k, v := range matches {
+		targets[k] = targetKey(prefix, *v.Key, targetPrefix, rename, layout)
+		if targetBucket == myConfig.Bucket && targets[k] == *v.Key && (move || !replace) {
+			return nil, fmt.Errorf("target of file {%s} is the file itself", *v.Key)
+		}
+		if i, ok := sources[targets[k]]; ok {
+			if move {
+				return nil, fmt.Errorf("files {%s} and {%s} have the same target {%s}", *matches[i].Key, *v.Key, targets[k])
+			}
+			log.Printf("[%d] file {%s} will overwrite the copy of file {%s} at {%s}", k, *v.Key, *matches[i].Key, targets[k])
+		}
+		sources[targets[k]] = k
+	}
+	return targets, nil
+}
+
+// define a chave de destino do objeto, na gravação em sub pastas o
+// caminho da chave abaixo do prefixo é mantido e o renomeio é aplicado
+// apenas ao nome do arquivo
+func targetKey(prefix string, key string, targetPrefix string, rename string, layout string) string {
+	name := parseName(key, rename)
+	if layout == LayoutTree {
+		if dir := path.Dir(strings.TrimPrefix(key, prefix)); dir != "." {
+			name = dir + "/" + name
+		}
+	}
+	return targetPrefix + name
+}
+
+// define a origem da cópia codificada para a url
+func copySource(bucket string, key string) string {
+	segments := strings.Split(key, "/")
+	for k, v := range segments {
+		segments[k] = url.PathEscape(v)
+	}
+	return url.PathEscape(bucket) + "/" + strings.Join(segments, "/")
+}
+
+// copia o objeto no próprio serviço, se forem informados metadados os
+// metadados da origem são substituídos, objetos maiores que 5GB são
+// copiados em partes. Retorna o tamanho e o ETag do objeto copiado
+func copyObject(key string, targetBucket string, targetKey string, metaData map[string]string) (size int64, etag string, err error) {
+	// identifica as propriedades da origem
+	head, err := s3client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(myConfig.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("unable to read properties of object, %s", err)
+	}
+	if head.ContentLength > maxCopySize {
+		etag, err = copyMultipart(key, head, targetBucket, targetKey, metaData)
+		return head.ContentLength, etag, err
+	}
+	// realiza a cópia com uma única requisição, a cópia só é aceita se
+	// a origem não foi alterada desde a leitura das propriedades
+	input := &s3.CopyObjectInput{
+		Bucket:            aws.String(targetBucket),
+		Key:               aws.String(targetKey),
+		CopySource:        aws.String(copySource(myConfig.Bucket, key)),
+		CopySourceIfMatch: head.ETag,
+		StorageClass:      types.StorageClass(head.StorageClass),
+	}
+	// mantém a criptografia da origem
+	if head.ServerSideEncryption != "" {
+		input.ServerSideEncryption = head.ServerSideEncryption
+		input.SSEKMSKeyId = head.SSEKMSKeyId
+		input.BucketKeyEnabled = head.BucketKeyEnabled
+	}
+	// na substituição dos metadados os cabeçalhos da origem são mantidos
+	if metaData != nil {
+		input.MetadataDirective = types.MetadataDirectiveReplace
+		input.Metadata = metaData
+		input.ContentType = head.ContentType
+		input.CacheControl = head.CacheControl
+		input.ContentEncoding = head.ContentEncoding
+		input.ContentDisposition = head.ContentDisposition
+		input.ContentLanguage = head.ContentLanguage
+		input.Expires = head.Expires
+	}
+	output, err := s3client.CopyObject(context.TODO(), input)
+	if err != nil {
+		return 0, "", fmt.Errorf("unable to copy object, %s", err)
+	}
+	if output.CopyObjectResult != nil {
+		etag = aws.ToString(output.CopyObjectResult.ETag)
+	}
+	return head.ContentLength, etag, nil
+}
+
+// confirma se o objeto copiado existe no destino com o tamanho e o ETag
+// retornados pela cópia
+func confirmCopy(bucket string, key string, size int64, etag string) error {
+	head, err := s3client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to read properties of copied object, %s", err)
+	}
+	if head.ContentLength != size {
+		return fmt.Errorf("size of copied object {%d} differs from source {%d}", head.ContentLength, size)
+	}
+	if etag != "" && aws.ToString(head.ETag) != etag {
+		return fmt.Errorf("etag of copied object {%s} differs from copy result {%s}", aws.ToString(head.ETag), etag)
+	}
+	return nil
+}
+
+// copia o objeto em partes usando o envio multipart, em caso de falha o
+// envio é cancelado para não manter as partes no bucket. As tags e a
+// criptografia da origem são mantidas como na cópia em uma requisição
+func copyMultipart(key string, head *s3.HeadObjectOutput, targetBucket string, targetKey string, metaData map[string]string) (etag string, err error) {
+	// mantém os metadados da origem se não foram informados
+	if metaData == nil {
+		metaData = head.Metadata
+	}
+	tags, err := objectTags(key)
+	if err != nil {
+		return "", fmt.Errorf("unable to read tags of object, %s", err)
+	}
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:             aws.String(targetBucket),
+		Key:                aws.String(targetKey),
+		Metadata:           metaData,
+		ContentType:        head.ContentType,
+		CacheControl:       head.CacheControl,
+		ContentEncoding:    head.ContentEncoding,
+		ContentDisposition: head.ContentDisposition,
+		ContentLanguage:    head.ContentLanguage,
+		Expires:            head.Expires,
+		StorageClass:       types.StorageClass(head.StorageClass),
+	}
+	if len(tags) > 0 {
+		input.Tagging = aws.String(encodeTags(tags))
+	}
+	if head.ServerSideEncryption != "" {
+		input.ServerSideEncryption = head.ServerSideEncryption
+		input.SSEKMSKeyId = head.SSEKMSKeyId
+		input.BucketKeyEnabled = head.BucketKeyEnabled
+	}
+	output, err := s3client.CreateMultipartUpload(context.TODO(), input)
+	if err != nil {
+		return "", fmt.Errorf("unable to create multipart upload, %s", err)
+	}
+	uploadId := output.UploadId
+	defer func() {
+		if err == nil {
+			return
+		}
+		_, abortErr := s3client.AbortMultipartUpload(context.TODO(), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(targetBucket),
+			Key:      aws.String(targetKey),
+			UploadId: uploadId,
+		})
+		if abortErr != nil {
+			log.Printf("unable to abort multipart copy {%s} of key {%s}, %s", aws.ToString(uploadId), targetKey, abortErr)
+		}
+	}()
+	// define o tamanho das partes
+	size := head.ContentLength
+	partSize := copyPartSize(size)
+	total := int((size + partSize - 1) / partSize)
+	// copia as partes
+	parts := make([]types.CompletedPart, total)
+	errs := make([]error, total)
+	runWorkers(total, manager.DefaultUploadConcurrency, func(k int) {
+		start := int64(k) * partSize
+		end := start + partSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		part, err := s3client.UploadPartCopy(context.TODO(), &s3.UploadPartCopyInput{
+			Bucket:            aws.String(targetBucket),
+			Key:               aws.String(targetKey),
+			UploadId:          uploadId,
+			PartNumber:        int32(k + 1),
+			CopySource:        aws.String(copySource(myConfig.Bucket, key)),
+			CopySourceIfMatch: head.ETag,
+			CopySourceRange:   aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+		})
+		if err != nil {
+			errs[k] = fmt.Errorf("unable to copy part %d, %s", k+1, err)
+			return
+		}
+		parts[k] = types.CompletedPart{PartNumber: int32(k + 1), ETag: part.CopyPartResult.ETag}
+	})
+	for _, v := range errs {
+		if v != nil {
+			return "", v
+		}
+	}
+	// conclui a cópia com as partes ordenadas
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	complete, err := s3client.CompleteMultipartUpload(context.TODO(), &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(targetBucket),
+		Key:             aws.String(targetKey),
+		UploadId:        uploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to complete multipart copy, %s", err)
+	}
+	return aws.ToString(complete.ETag), nil
+}
+
+// define o tamanho das partes da cópia respeitando o limite de partes do
+// envio multipart
+func copyPartSize(size int64) int64 {
+	return clampPartSize(size, partSizeFor(size))
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// define as faixas de idade dos objetos em dias
+var ageBuckets = []struct {
+	Label string
+	Days  int
+}{
+	{"<1d", 1},
+	{"1d-7d", 7},
+	{"7d-30d", 30},
+	{"30d-90d", 90},
+	{"90d-365d", 365},
+	{">365d", 0},
+}
+
+// define a utilização de um prefixo
+type usageEntry struct {
+	Prefix       string                 `json:"prefix"`
+	Objects      int64                  `json:"objects"`
+	Size         int64                  `json:"size"`
+	StorageClass map[string]*usageTotal `json:"storage_class,omitempty"`
+	Age          map[string]*usageTotal `json:"age,omitempty"`
+}
+
+// define a quantidade de objetos e o total de bytes
+type usageTotal struct {
+	Objects int64 `json:"objects"`
+	Size    int64 `json:"size"`
+}
+
+// soma o objeto ao total
+func (p *usageTotal) add(size int64) {
+	p.Objects++
+	p.Size += size
+}
+
+// processa o comando de utilização do bucket por prefixo
+func processUsage(args []string) {
+	// identifica os flags informados
+	cmdUsage := flag.NewFlagSet("du", flag.ExitOnError)
+	// define os parametros para sobrescrever o padrão configurado
+	pBucketFlags := addBucketFlags(cmdUsage)
+	// define os parametros para utilização específicos para este método
+	pFilter := cmdUsage.String("f", "**", "filter to select files")
+	pDepth := cmdUsage.Int("depth", 1, "number of sub folder levels below the bucket prefix summarized separately")
+	pStorageClass := cmdUsage.Bool("sc", false, "show the usage of each storage class")
+	pAge := cmdUsage.Bool("age", false, "show the usage by age of the objects (<1d, 1d-7d, 7d-30d, 30d-90d, 90d-365d, >365d)")
+	pJSON := cmdUsage.Bool("json", false, "show the usage of each prefix as a json line")
+	// processa os parametros
+	err := cmdUsage.Parse(args)
+	if err != nil {
+		cmdUsage.Usage()
+		os.Exit(1)
+	}
+	// aplica os parametros de acesso ao bucket
+	pBucketFlags.apply()
+	// valida o filtro
+	if *pFilter == "" {
+		log.Fatalf("file name filter not provided")
+	}
+	// valida a profundidade
+	if *pDepth < 0 {
+		log.Fatalf("depth {%d} is invalid", *pDepth)
+	}
+	// valida se há parametros suficientes
+	if myConfig.Bucket == "" {
+		cmdUsage.Usage()
+		os.Exit(1)
+	}
+	// inicializa o serviço da aws
+	err = connect(*pBucketFlags.Role)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// calcula a utilização
+	err = bucketUsage(*pFilter, *pBucketFlags.Prefix, *pDepth, *pStorageClass, *pAge, *pJSON)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// calcula e exibe a quantidade de objetos e o total de bytes de cada sub
+// pasta do prefixo até a profundidade informada
+func bucketUsage(filter string, prefix string, depth int, storageClass bool, age bool, jsonOutput bool) error {
+	// ajusta os campos traduzindo as variaveis se utilizadas
+	prefix = parseName("", prefix)
+	filter = parseName("", filter)
+	// define a expressão regular para realizar a pesquisa
+	pattern, err := keyPattern(prefix, filter)
+	if err != nil {
+		return fmt.Errorf("unable filter files, %s", err)
+	}
+	// soma os objetos de cada prefixo
+	now := time.Now()
+	usage := make(map[string]*usageEntry)
+	total := &usageEntry{Prefix: prefix}
+	count, err := listObjects(prefix, func(obj types.Object) error {
+		key := aws.ToString(obj.Key)
+		if !pattern.MatchString(key) {
+			return nil
+		}
+		name := usagePrefix(prefix, key, depth)
+		entry, ok := usage[name]
+		if !ok {
+			entry = &usageEntry{Prefix: name}
+			usage[name] = entry
+		}
+		for _, v := range []*usageEntry{entry, total} {
+			v.Objects++
+			v.Size += obj.Size
+			if storageClass {
+				v.addStorageClass(string(obj.StorageClass), obj.Size)
+			}
+			if age {
+				v.addAge(ageBucket(aws.ToTime(obj.LastModified), now), obj.Size)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("total of keys verified in bucket {%s}: %d", myConfig.Bucket, count)
+	// exibe a utilização ordenada pelo prefixo
+	names := make([]string, 0, len(usage))
+	for k := range usage {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	enc := json.NewEncoder(os.Stdout)
+	for _, v := range names {
+		if jsonOutput {
+			enc.Encode(usage[v])
+		} else {
+			printUsage(usage[v])
+		}
+	}
+	log.Printf("total usage of prefix {%s}: %d objects, %d bytes (%s)", prefix, total.Objects, total.Size, humanSize(total.Size))
+	if !jsonOutput && (storageClass || age) && len(names) > 1 {
+		printUsage(total)
+	}
+	return nil
+}
+
+// soma o objeto ao total da classe de armazenamento
+func (p *usageEntry) addStorageClass(storageClass string, size int64) {
+	// a classe padrão não é retornada em alguns serviços compatíveis
+	if storageClass == "" {
+		storageClass = string(types.ObjectStorageClassStandard)
+	}
+	if p.StorageClass == nil {
+		p.StorageClass = make(map[string]*usageTotal)
+	}
+	if _, ok := p.StorageClass[storageClass]; !ok {
+		p.StorageClass[storageClass] = &usageTotal{}
+	}
+	p.StorageClass[storageClass].add(size)
+}
+
+// soma o objeto ao total da faixa de idade
+func (p *usageEntry) addAge(label string, size int64) {
+	if p.Age == nil {
+		p.Age = make(map[string]*usageTotal)
+	}
+	if _, ok := p.Age[label]; !ok {
+		p.Age[label] = &usageTotal{}
+	}
+	p.Age[label].add(size)
+}
+
+// exibe a utilização do prefixo em formato legível
+func printUsage(entry *usageEntry) {
+	fmt.Printf("%12d %16d %10s %s\n", entry.Objects, entry.Size, humanSize(entry.Size), entry.Prefix)
+	if len(entry.StorageClass) > 0 {
+		names := make([]string, 0, len(entry.StorageClass))
+		for k := range entry.StorageClass {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, v := range names {
+			t := entry.StorageClass[v]
+			fmt.Printf("%12d %16d %10s   %s\n", t.Objects, t.Size, humanSize(t.Size), v)
+		}
+	}
+	for _, v := range ageBuckets {
+		if t, ok := entry.Age[v.Label]; ok {
+			fmt.Printf("%12d %16d %10s   %s\n", t.Objects, t.Size, humanSize(t.Size), v.Label)
+		}
+	}
+}
+
+// define o prefixo em que o objeto é totalizado, mantendo até a
+// quantidade de sub pastas informada abaixo do prefixo
+func usagePrefix(prefix string, key string, depth int) string {
+	dirs := strings.Split(strings.TrimPrefix(key, prefix), "/")
+	// desconsidera o nome do arquivo
+	dirs = dirs[:len(dirs)-1]
+	if len(dirs) > depth {
+		dirs = dirs[:depth]
+	}
+	if len(dirs) == 0 {
+		return prefix
+	}
+	return prefix + strings.Join(dirs, "/") + "/"
+}
+
+// define a faixa de idade do objeto
+func ageBucket(modified time.Time, now time.Time) string {
+	days := now.Sub(modified).Hours() / 24
+	for _, v := range ageBuckets {
+		if v.Days > 0 && days < float64(v.Days) {
+			return v.Label
+		}
+	}
+	return ageBuckets[len(ageBuckets)-1].Label
+}
+
+// formata a quantidade de bytes com a unidade
+func humanSize(n int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+	size := float64(n)
+	k := 0
+	for size >= 1024 && k < len(units)-1 {
+		size /= 1024
+		k++
+	}
+	if k == 0 {
+		return fmt.Sprintf("%d%s", n, units[k])
+	}
+	return fmt.Sprintf("%.1f%s", size, units[k])
+}
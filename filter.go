@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// define as variaveis dos grupos capturados pela expressão regular que
+// podem ser usadas no renomeio, #G1 a #G9 ou #G{name}
+var groupVars = regexp.MustCompile(`#G(?:\{(\w+)\}|([0-9]))`)
+
+// define a duração em dias, seguida opcionalmente de horas e minutos
+var dayDuration = regexp.MustCompile(`^([0-9]+)d(.*)$`)
+
+// define o tamanho com a unidade opcional
+var sizeUnit = regexp.MustCompile(`^([0-9]+)\s*(B|K|KB|M|MB|G|GB|T|TB)?$`)
+
+// define os parametros de seleção dos arquivos no envio e na recepção
+type filterFlags struct {
+	Glob        *string
+	Regexp      *string
+	Exclude     *stringList
+	ExcludeFile *string
+	Newer       *string
+	Older       *string
+	MinSize     *string
+	MaxSize     *string
+}
+
+// define uma lista de valores de um parametro que pode ser repetido
+type stringList []string
+
+// retorna os valores separados por virgula
+func (p *stringList) String() string {
+	return strings.Join(*p, ",")
+}
+
+// adiciona o valor a lista
+func (p *stringList) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+// adiciona os parametros de seleção dos arquivos ao comando
+func addFilterFlags(cmd *flag.FlagSet) *filterFlags {
+	p := &filterFlags{
+		Glob:        cmd.String("f", "", "filter to select files"),
+		Regexp:      cmd.String("fr", "", "regular expression to select files instead of the filter, the capture groups can be used in the name of target file (#G1 to #G9 or #G{name})"),
+		Exclude:     &stringList{},
+		ExcludeFile: cmd.String("xf", "", "file with the filters of the files that will not be selected, one filter per line"),
+		Newer:       cmd.String("newer", "", "select only files modified after the duration (sintax 30m, 2h, 7d) or date (sintax 2006-01-02 or 2006-01-02T15:04:05)"),
+		Older:       cmd.String("older", "", "select only files modified before the duration (sintax 30m, 2h, 7d) or date (sintax 2006-01-02 or 2006-01-02T15:04:05)"),
+		MinSize:     cmd.String("minsize", "", "select only files with at least this size (sintax 1, 10KB, 5MB, 2GB)"),
+		MaxSize:     cmd.String("maxsize", "", "select only files with at most this size, must be greater than zero (sintax 1, 10KB, 5MB, 2GB)"),
+	}
+	cmd.Var(p.Exclude, "x", "filter of the files that will not be selected, can be repeated")
+	return p
+}
+
+// cria o filtro com os parametros informados
+func (p *filterFlags) filter() (*fileFilter, error) {
+	if *p.Glob == "" && *p.Regexp == "" {
+		return nil, fmt.Errorf("file name filter not provided")
+	}
+	if *p.Glob != "" && *p.Regexp != "" {
+		return nil, fmt.Errorf("filter {%s} and regular expression filter {%s} can not be used together", *p.Glob, *p.Regexp)
+	}
+	filter := &fileFilter{Glob: *p.Glob}
+	if *p.Regexp != "" {
+		pattern, err := regexp.Compile(*p.Regexp)
+		if err != nil {
+			return nil, fmt.Errorf("regular expression {%s} is invalid, %s", *p.Regexp, err)
+		}
+		filter.Regexp = pattern
+	}
+	// identifica os filtros de exclusão
+	excludes := *p.Exclude
+	if *p.ExcludeFile != "" {
+		lines, err := readExcludeFile(*p.ExcludeFile)
+		if err != nil {
+			return nil, err
+		}
+		excludes = append(excludes, lines...)
+	}
+	for _, v := range excludes {
+		match, err := relativeMatcher(v)
+		if err != nil {
+			return nil, fmt.Errorf("exclude filter {%s} is invalid, %s", v, err)
+		}
+		filter.Exclude = append(filter.Exclude, v)
+		filter.excludes = append(filter.excludes, match)
+	}
+	// identifica os limites da data de alteração
+	now := time.Now()
+	var err error
+	if *p.Newer != "" {
+		filter.Newer, err = parseAge(*p.Newer, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if *p.Older != "" {
+		filter.Older, err = parseAge(*p.Older, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !filter.Newer.IsZero() && !filter.Older.IsZero() && !filter.Newer.Before(filter.Older) {
+		return nil, fmt.Errorf("no file can be modified after %s and before %s", filter.Newer.Format(time.RFC3339), filter.Older.Format(time.RFC3339))
+	}
+	// identifica os limites de tamanho
+	if *p.MinSize != "" {
+		filter.MinSize, err = parseSize(*p.MinSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if *p.MaxSize != "" {
+		filter.MaxSize, err = parseSize(*p.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		// o tamanho zero é usado para indicar que não há limite máximo
+		if filter.MaxSize == 0 {
+			return nil, fmt.Errorf("maximum size must be greater than zero")
+		}
+	}
+	if filter.MaxSize > 0 && filter.MinSize > filter.MaxSize {
+		return nil, fmt.Errorf("minimum size %d is larger than maximum size %d", filter.MinSize, filter.MaxSize)
+	}
+	return filter, nil
+}
+
+// converte o tamanho informado em bytes, aceita as unidades KB, MB, GB
+// e TB com base 1024
+func parseSize(value string) (int64, error) {
+	match := sizeUnit.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(value)))
+	if match == nil {
+		return 0, fmt.Errorf("size {%s} is invalid", value)
+	}
+	size, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("size {%s} is invalid, %s", value, err)
+	}
+	var unit int64 = 1
+	switch match[2] {
+	case "K", "KB":
+		unit = 1024
+	case "M", "MB":
+		unit = 1024 * 1024
+	case "G", "GB":
+		unit = 1024 * 1024 * 1024
+	case "T", "TB":
+		unit = 1024 * 1024 * 1024 * 1024
+	}
+	if size > math.MaxInt64/unit {
+		return 0, fmt.Errorf("size {%s} is too large", value)
+	}
+	return size * unit, nil
+}
+
+// converte a duração ou a data informada no horário limite, a duração é
+// subtraída do horário atual e aceita dias com o sufixo "d"
+func parseAge(value string, now time.Time) (time.Time, error) {
+	if match := dayDuration.FindStringSubmatch(value); match != nil {
+		// limita os dias para que a duração não estoure o int64
+		days, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || days > math.MaxInt64/int64(24*time.Hour) {
+			return time.Time{}, fmt.Errorf("duration {%s} is out of range", value)
+		}
+		age := time.Duration(days) * 24 * time.Hour
+		if match[2] != "" {
+			rest, err := time.ParseDuration(match[2])
+			if err != nil {
+				return time.Time{}, fmt.Errorf("duration {%s} is invalid, %s", value, err)
+			}
+			if rest < 0 {
+				return time.Time{}, fmt.Errorf("duration {%s} must not be negative", value)
+			}
+			if rest > math.MaxInt64-age {
+				return time.Time{}, fmt.Errorf("duration {%s} is out of range", value)
+			}
+			age += rest
+		}
+		return now.Add(-age), nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("duration {%s} must not be negative", value)
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("duration or date {%s} is invalid", value)
+}
+
+// lê os filtros de exclusão do arquivo, as linhas vazias ou iniciadas
+// por # são desconsideradas
+func readExcludeFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open exclude file {%s}, %s", file, err)
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read exclude file {%s}, %s", file, err)
+	}
+	return lines, nil
+}
+
+// define a seleção dos arquivos no envio e na recepção
+type fileFilter struct {
+	// filtro wildcard
+	Glob string
+	// expressão regular usada no lugar do filtro wildcard
+	Regexp *regexp.Regexp
+	// filtros dos arquivos que não serão selecionados
+	Exclude  []string
+	excludes []func(rel string) bool
+	// seleciona apenas os arquivos alterados após o horário
+	Newer time.Time
+	// seleciona apenas os arquivos alterados antes do horário
+	Older time.Time
+	// tamanho mínimo e máximo dos arquivos selecionados
+	MinSize int64
+	MaxSize int64
+}
+
+// verifica se há critérios de seleção além do filtro
+func (p *fileFilter) restricted() bool {
+	return len(p.Exclude) > 0 || !p.Newer.IsZero() || !p.Older.IsZero() || p.MinSize > 0 || p.MaxSize > 0
+}
+
+// verifica se o arquivo deve ser desconsiderado e retorna o motivo, o
+// nome é o caminho relativo à pasta local ou ao prefixo do bucket
+func (p *fileFilter) exclusion(name string, modified time.Time, size int64) string {
+	for k, v := range p.excludes {
+		if v(name) {
+			return fmt.Sprintf("matches exclude filter {%s}", p.Exclude[k])
+		}
+	}
+	if !p.Newer.IsZero() && !modified.After(p.Newer) {
+		return fmt.Sprintf("modified at %s, not after %s", modified.Local().Format(time.RFC3339), p.Newer.Local().Format(time.RFC3339))
+	}
+	if !p.Older.IsZero() && !modified.Before(p.Older) {
+		return fmt.Sprintf("modified at %s, not before %s", modified.Local().Format(time.RFC3339), p.Older.Local().Format(time.RFC3339))
+	}
+	if p.MinSize > 0 && size < p.MinSize {
+		return fmt.Sprintf("size %d is smaller than minimum size %d", size, p.MinSize)
+	}
+	if p.MaxSize > 0 && size > p.MaxSize {
+		return fmt.Sprintf("size %d is larger than maximum size %d", size, p.MaxSize)
+	}
+	return ""
+}
+
+// retorna o filtro usado na seleção para exibição nos logs
+func (p *fileFilter) String() string {
+	if p.Regexp != nil {
+		return p.Regexp.String()
+	}
+	return p.Glob
+}
+
+// verifica se todos os grupos usados no renomeio existem na expressão
+// regular
+func (p *fileFilter) validateRename(mask string) error {
+	for _, v := range groupVars.FindAllStringSubmatch(mask, -1) {
+		if p.Regexp == nil {
+			return fmt.Errorf("capture group {%s} can only be used with a regular expression filter", v[0])
+		}
+		if groupIndex(p.Regexp, v) < 0 {
+			return fmt.Errorf("capture group {%s} not found in regular expression {%s}", v[0], p.Regexp)
+		}
+	}
+	return nil
+}
+
+// traduz na máscara de renomeio os grupos capturados pela expressão
+// regular no nome informado
+func (p *fileFilter) rename(mask string, name string) string {
+	if p.Regexp == nil {
+		return mask
+	}
+	match := p.Regexp.FindStringSubmatch(name)
+	if match == nil {
+		return mask
+	}
+	return groupVars.ReplaceAllStringFunc(mask, func(v string) string {
+		index := groupIndex(p.Regexp, groupVars.FindStringSubmatch(v))
+		if index < 0 {
+			return ""
+		}
+		return match[index]
+	})
+}
+
+// seleciona os objetos do bucket abaixo do prefixo que atendem ao
+// filtro, a expressão regular é aplicada à chave sem o prefixo
+func (p *fileFilter) objects(prefix string) ([]types.Object, error) {
+	var match func(key string) bool
+	if p.Regexp != nil {
+		match = func(key string) bool {
+			return p.Regexp.MatchString(strings.TrimPrefix(key, prefix))
+		}
+	} else {
+		if !p.restricted() {
+			return selectObjects(p.Glob, prefix)
+		}
+		pattern, err := keyPattern(prefix, p.Glob)
+		if err != nil {
+			return nil, fmt.Errorf("unable filter files, %s", err)
+		}
+		match = pattern.MatchString
+	}
+	// aplica as exclusões durante a listagem
+	var excluded int
+	matches, count, err := selectMatching(prefix, func(obj types.Object) bool {
+		if !match(*obj.Key) {
+			return false
+		}
+		if reason := p.exclusion(strings.TrimPrefix(*obj.Key, prefix), aws.ToTime(obj.LastModified), obj.Size); reason != "" {
+			excluded++
+			log.Printf("file {%s} excluded, %s", *obj.Key, reason)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	// exibe a quantidade de objetos lidos do bucket
+	log.Printf("total of keys verified in bucket {%s}: %d excluded: %d", myConfig.Bucket, count, excluded)
+	return matches, nil
+}
+
+// seleciona os arquivos locais que atendem ao filtro, a expressão
+// regular é aplicada ao nome do arquivo ou ao caminho relativo à pasta
+// no envio recursivo
+func (p *fileFilter) files(folder string, recursive bool) ([]string, error) {
+	if p.Regexp == nil && !p.restricted() {
+		return listFiles(folder, p.Glob, recursive)
+	}
+	glob := p.Glob
+	if p.Regexp != nil {
+		glob = "*"
+	}
+	matches, err := listFiles(folder, glob, recursive)
+	if err != nil {
+		return nil, err
+	}
+	// aplica as exclusões após a listagem
+	var files []string
+	var excluded int
+	for _, v := range matches {
+		name := relativeName(folder, v, recursive)
+		if p.Regexp != nil && !p.Regexp.MatchString(name) {
+			continue
+		}
+		stat, err := os.Stat(v)
+		if err != nil {
+			return nil, err
+		}
+		if reason := p.exclusion(name, stat.ModTime(), stat.Size()); reason != "" {
+			excluded++
+			log.Printf("file {%s} excluded, %s", v, reason)
+			continue
+		}
+		files = append(files, v)
+	}
+	if p.restricted() {
+		log.Printf("total of files verified in folder {%s}: %d excluded: %d", folder, len(files)+excluded, excluded)
+	}
+	return files, nil
+}
+
+// define o nome do arquivo comparado com o filtro, no envio recursivo é
+// o caminho relativo à pasta
+func relativeName(folder string, file string, recursive bool) string {
+	if recursive {
+		if rel, err := filepath.Rel(folder, file); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(file)
+}
+
+// identifica o indice do grupo da expressão regular referenciado pela
+// variavel, retorna -1 se o grupo não existir
+func groupIndex(pattern *regexp.Regexp, group []string) int {
+	name := group[1]
+	if name == "" {
+		name = group[2]
+	}
+	index := pattern.SubexpIndex(name)
+	if index < 0 {
+		if n, err := strconv.Atoi(name); err == nil && n <= pattern.NumSubexp() {
+			index = n
+		}
+	}
+	return index
+}
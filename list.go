@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Define as formas de exibição da listagem
+const (
+	OutputShort = "short"
+	OutputLong  = "long"
+	OutputJSON  = "json"
+)
+
+// Define uma entrada da listagem do bucket no formato json
+type listEntry struct {
+	Key          string     `json:"key,omitempty"`
+	Prefix       string     `json:"prefix,omitempty"`
+	Size         int64      `json:"size"`
+	LastModified *time.Time `json:"last_modified,omitempty"`
+	StorageClass string     `json:"storage_class,omitempty"`
+	ETag         string     `json:"etag,omitempty"`
+}
+
+// processa o comando de listagem do bucket
+func processList(args []string) {
+	// identifica os flags informados
+	cmdList := flag.NewFlagSet("ls", flag.ExitOnError)
+	// define os parametros para sobrescrever o padrão configurado
+	pBucketFlags := addBucketFlags(cmdList)
+	// define os parametros para utilização específicos para este método
+	pFilter := cmdList.String("f", "**", "filter to select files")
+	pDelimiter := cmdList.Bool("d", false, "list only the first level below the bucket prefix showing sub folders as directories")
+	pLong := cmdList.Bool("l", false, "show size, last modified date, storage class and etag of each object")
+	pJSON := cmdList.Bool("json", false, "show each object as a json line")
+	// processa os parametros
+	err := cmdList.Parse(args)
+	if err != nil {
+		cmdList.Usage()
+		os.Exit(1)
+	}
+	// aplica os parametros de acesso ao bucket
+	pBucketFlags.apply()
+	// valida o filtro
+	if *pFilter == "" {
+		log.Fatalf("file name filter not provided")
+	}
+	// define a forma de exibição
+	output := OutputShort
+	if *pLong {
+		output = OutputLong
+	}
+	if *pJSON {
+		output = OutputJSON
+	}
+	// valida se há parametros suficientes
+	if myConfig.Bucket == "" {
+		cmdList.Usage()
+		os.Exit(1)
+	}
+	// inicializa o serviço da aws
+	err = connect(*pBucketFlags.Role)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// executa a listagem
+	err = listBucket(*pFilter, *pBucketFlags.Prefix, *pDelimiter, output)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Lista os objetos do bucket que atendem ao filtro especificado, com o
+// delimitador apenas o primeiro nível abaixo do prefixo é listado
+func listBucket(filter string, prefix string, delimiter bool, output string) error {
+	// ajusta os campos traduzindo as variaveis se utilizadas
+	prefix = parseName("", prefix)
+	filter = parseName("", filter)
+	// define a expressão regular para realizar a pesquisa
+	pattern, err := keyPattern(prefix, filter)
+	if err != nil {
+		return fmt.Errorf("unable filter files, %s", err)
+	}
+	// define os parametros de listagem
+	params := &s3.ListObjectsV2Input{
+		Bucket: aws.String(myConfig.Bucket),
+		Prefix: aws.String(prefix),
+	}
+	if delimiter {
+		params.Delimiter = aws.String("/")
+	}
+	// define o paginador
+	paginator := s3.NewListObjectsV2Paginator(s3client, params, func(o *s3.ListObjectsV2PaginatorOptions) {
+		o.Limit = 1000
+	})
+	// processa a listagem das páginas
+	var objects, dirs, size int64
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return fmt.Errorf("unable to list bucket, %s", err)
+		}
+		// exibe as sub pastas
+		for _, v := range page.CommonPrefixes {
+			dir := aws.ToString(v.Prefix)
+			if !pattern.MatchString(strings.TrimSuffix(dir, "/")) {
+				continue
+			}
+			dirs++
+			printEntry(os.Stdout, listEntry{Prefix: dir}, output)
+		}
+		// exibe os objetos
+		for _, v := range page.Contents {
+			key := aws.ToString(v.Key)
+			if !pattern.MatchString(key) {
+				continue
+			}
+			objects++
+			size += v.Size
+			printEntry(os.Stdout, listEntry{
+				Key:          key,
+				Size:         v.Size,
+				LastModified: v.LastModified,
+				StorageClass: string(v.StorageClass),
+				ETag:         strings.Trim(aws.ToString(v.ETag), `"`),
+			}, output)
+		}
+	}
+	// exibe o total listado
+	if output == OutputLong {
+		log.Printf("total of keys listed in bucket {%s}: %d objects, %d directories, %d bytes", myConfig.Bucket, objects, dirs, size)
+	}
+	return nil
+}
+
+// exibe a entrada da listagem no formato informado, as sub pastas possuem
+// apenas o prefixo
+func printEntry(w io.Writer, entry listEntry, output string) {
+	name := entry.Key
+	if entry.Prefix != "" {
+		name = entry.Prefix
+	}
+	switch output {
+	case OutputJSON:
+		json.NewEncoder(w).Encode(entry)
+	case OutputLong:
+		if entry.Prefix != "" {
+			fmt.Fprintf(w, "%19s %14s %-20s %-34s %s\n", "", "DIR", "", "", name)
+			return
+		}
+		fmt.Fprintf(w, "%19s %14d %-20s %-34s %s\n", aws.ToTime(entry.LastModified).Local().Format("2006-01-02 15:04:05"), entry.Size, entry.StorageClass, entry.ETag, name)
+	default:
+		fmt.Fprintln(w, name)
+	}
+}
@@ -3,19 +3,24 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"net"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
@@ -29,10 +34,23 @@ var (
 	myConfig *Config
 	// define um client para o serviço s3 da aws
 	s3client *s3.Client
+	// região usada pelo client s3
+	s3region string
 	// define o gerador de numeros aleatórios
 	random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	// protege o gerador de numeros aleatórios, que não pode ser usado
+	// em paralelo
+	randomMutex sync.Mutex
 	// indica se deve realizar o debug de informações importantes
 	debug = false
+	// diretório do arquivo de configuração
+	configDir string
+)
+
+// Define as formas de gravação dos arquivos recebidos na pasta local
+const (
+	LayoutFlat = "flat"
+	LayoutTree = "tree"
 )
 
 const (
@@ -51,7 +69,9 @@ const (
 #FE = file extension with dot
 #R1 = random number 1 digit 0-9
 #R2 = random number 2 digits 00-99
-#R4 = random number 4 digits 0000-9999`
+#R4 = random number 4 digits 0000-9999
+#G1 to #G9 = capture group of the regular expression filter
+#G{name} = named capture group of the regular expression filter`
 )
 
 func main() {
@@ -59,6 +79,17 @@ func main() {
 	help := "Usage:\n"
 	help += " s3 get -?\n"
 	help += " s3 put -?\n"
+	help += " s3 ls -?\n"
+	help += " s3 rm -?\n"
+	help += " s3 cp -?\n"
+	help += " s3 mv -?\n"
+	help += " s3 presign -?\n"
+	help += " s3 stat -?\n"
+	help += " s3 du -?\n"
+	help += " s3 tag -?\n"
+	help += " s3 bucket -?\n"
+	help += " s3 sync put -?\n"
+	help += " s3 sync get -?\n"
 	help += " s3 config local -?\n"
 	help += " s3 config s3 -?\n"
 	help += " s3 config vault -?\n"
@@ -74,7 +105,7 @@ func main() {
 	// 1) variavel de ambiente S3_CONFIG
 	// 2) diretório padrão do usuário
 	// 3) diretório da aplicação
-	configDir := os.Getenv("S3_CONFIG")
+	configDir = os.Getenv("S3_CONFIG")
 	if configDir == "" {
 		dir, err1 := os.UserHomeDir()
 		if err1 != nil {
@@ -106,6 +137,26 @@ func main() {
 		processGet(os.Args[2:])
 	case "put":
 		processPut(os.Args[2:])
+	case "ls":
+		processList(os.Args[2:])
+	case "rm":
+		processRemove(os.Args[2:])
+	case "cp":
+		processCopy(os.Args[2:])
+	case "mv":
+		processMove(os.Args[2:])
+	case "presign":
+		processPresign(os.Args[2:])
+	case "stat":
+		processStat(os.Args[2:])
+	case "du":
+		processUsage(os.Args[2:])
+	case "tag":
+		processTag(os.Args[2:])
+	case "bucket":
+		processBucket(os.Args[2:])
+	case "sync":
+		processSync(os.Args[2:])
 	case "config":
 		args := os.Args[2:]
 		if len(args) == 0 {
@@ -255,17 +306,9 @@ func processConfigS3(args []string) {
 	// configura os metadados que serão gravados por padrão em todos os
 	// arquivos que forem enviados para o bucket
 	if *pMetaData != "" {
-		// inicializa o mapa de metadados
-		myConfig.Metadata = make(map[string]string)
-		// extrai e configura os metados informados
-		// os metadados seguem o padrão: key1=valor1;key2=valor2
-		values := strings.Split(*pMetaData, ";")
-		for k, v := range values {
-			keyvalue := strings.Split(v, "=")
-			if len(keyvalue) < 2 {
-				log.Fatalf("[%d] metadata {%s} is invalid", k, v)
-			}
-			myConfig.Metadata[strings.TrimSpace(keyvalue[0])] = strings.TrimSpace(keyvalue[1])
+		myConfig.Metadata, err = parseMetadata(*pMetaData)
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
 	// configura o endereço http do endpoint para acesso ao bucket
@@ -295,6 +338,7 @@ func processConfigLocal(args []string) {
 	cmdConfig := flag.NewFlagSet("local", flag.ExitOnError)
 	// define os parametros para utilização
 	pFolder := cmdConfig.String("folder", "", "default folder of file to upload or download")
+	pCheckpoint := cmdConfig.String("checkpoint", "", "folder of checkpoints used to resume interrupted transfers")
 	// processa os parametros
 	err := cmdConfig.Parse(args)
 	if err != nil || len(args) == 0 {
@@ -305,6 +349,10 @@ func processConfigLocal(args []string) {
 	if *pFolder != "" {
 		myConfig.LocalFolder = *pFolder
 	}
+	// configura a pasta dos checkpoints
+	if *pCheckpoint != "" {
+		myConfig.CheckpointFolder = *pCheckpoint
+	}
 	// grava as configurações
 	err = saveConfig()
 	if err != nil {
@@ -404,91 +452,185 @@ func processConfigVault(args []string) {
 	}
 }
 
-// processa o comando de download de arquivos
-func processGet(args []string) {
-	// identifica os flags informados
-	cmdGet := flag.NewFlagSet("get", flag.ExitOnError)
-	// define os parametros para sobrescrever o padrão configurado
-	pBucket := cmdGet.String("b", "", "bucket name")
-	pRegion := cmdGet.String("br", "", "bucket region")
-	pPartSize := cmdGet.Int("ps", 0, "size of each part of the file uploaded to the bucket (use 0 to automatic calculate)")
-	pEndPoint := cmdGet.String("ep", "", "url of bucket end point (sintax https://my-s3-url.com)")
-	pFolder := cmdGet.String("df", "", "default folder for files")
-	// define os parametros para utilização específicos para este método
-	pFilter := cmdGet.String("f", "", "filter to select files")
-	pRemove := cmdGet.Bool("rm", false, "remove files after transfer")
-	pRename := cmdGet.String("c", "", fmt.Sprintf("change the name of target file\n%s", renameVars))
-	pErrorNoFiles := cmdGet.Bool("enf", false, "terminate with exit code 1 if no files found")
-	pRole := cmdGet.String("r", "", "vault role name to access bucket")
-	// parametros adicionais
-	pBucketPrefix := cmdGet.String("bp", "", "bucket prefix (sub folder)")
-	pDebug := cmdGet.Bool("debug", false, "show additional information for debug")
-	// processa os parametros
-	err := cmdGet.Parse(args)
-	if err != nil || len(args) == 0 {
-		cmdGet.Usage()
-		os.Exit(1)
+// define os parametros de acesso ao bucket que são comuns aos comandos
+// e sobrescrevem o padrão configurado
+type bucketFlags struct {
+	Bucket   *string
+	Region   *string
+	PartSize *int
+	EndPoint *string
+	Folder   *string
+	Role     *string
+	Prefix   *string
+	Debug    *bool
+}
+
+// registra os parametros de acesso ao bucket no comando
+func addBucketFlags(cmd *flag.FlagSet) *bucketFlags {
+	return &bucketFlags{
+		Bucket:   cmd.String("b", "", "bucket name"),
+		Region:   cmd.String("br", "", "bucket region"),
+		PartSize: cmd.Int("ps", 0, "size of each part of the file uploaded to the bucket (use 0 to automatic calculate)"),
+		EndPoint: cmd.String("ep", "", "url of bucket end point (sintax https://my-s3-url.com)"),
+		Folder:   cmd.String("df", "", "default folder for files"),
+		Role:     cmd.String("r", "", "vault role name to access bucket"),
+		Prefix:   cmd.String("bp", "", "bucket prefix (sub folder)"),
+		Debug:    cmd.Bool("debug", false, "show additional information for debug"),
 	}
+}
+
+// aplica os parametros informados na configuração
+func (p *bucketFlags) apply() {
 	// define o modo de debug
-	if *pDebug {
+	if *p.Debug {
 		debug = true
 	}
 	// configura o bucket
-	if *pBucket != "" {
-		myConfig.Bucket = *pBucket
+	if *p.Bucket != "" {
+		myConfig.Bucket = *p.Bucket
 	}
 	// configura a região do bucket
-	if *pRegion != "" {
-		myConfig.Region = *pRegion
+	if *p.Region != "" {
+		myConfig.Region = *p.Region
 	}
 	// configura o tamanho das partes para o envio de arquivo multipart
-	if *pPartSize < 5*1024*1024 {
+	if *p.PartSize < 5*1024*1024 {
 		myConfig.PartSize = 0
 	} else {
-		myConfig.PartSize = *pPartSize
+		myConfig.PartSize = *p.PartSize
 	}
 	// configura o endereço http do endpoint para acesso ao bucket
-	if *pEndPoint != "" {
-		myConfig.EndPoint = *pEndPoint
+	if *p.EndPoint != "" {
+		myConfig.EndPoint = *p.EndPoint
 	}
 	// configura a pasta padrão onde estão ou serão gravados os arquivos
-	if *pFolder != "" {
-		myConfig.LocalFolder = *pFolder
+	if *p.Folder != "" {
+		myConfig.LocalFolder = *p.Folder
+	}
+	// ajusta o prefixo do bucket (sub pasta)
+	*p.Prefix = normalizePrefix(*p.Prefix)
+}
+
+// ajusta o prefixo do bucket (sub pasta) para terminar com a barra
+func normalizePrefix(prefix string) string {
+	if prefix != "" {
+		prefix = strings.TrimPrefix(prefix, "/")
+		if !strings.HasSuffix(prefix, "/") {
+			prefix = prefix + "/"
+		}
+	}
+	return prefix
+}
+
+// carrega as credenciais se necessário e inicializa o serviço da aws
+func connect(role string) error {
+	// carrega das credenciais do vault se necessário
+	if myConfig.AccessKey == "" || myConfig.SecretKey == "" {
+		err := loadCredentials(role)
+		if err != nil {
+			return err
+		}
 	}
+	// inicializa o serviço da aws
+	return configureAWSClient()
+}
+
+// converte os pares chave e valor no padrão key1=valor1;key2=valor2
+func parseMetadata(value string) (map[string]string, error) {
+	metaData := make(map[string]string)
+	values := strings.Split(value, ";")
+	for k, v := range values {
+		keyvalue := strings.Split(v, "=")
+		if len(keyvalue) < 2 {
+			return nil, fmt.Errorf("[%d] metadata {%s} is invalid", k, v)
+		}
+		metaData[strings.TrimSpace(keyvalue[0])] = strings.TrimSpace(keyvalue[1])
+	}
+	return metaData, nil
+}
+
+// processa o comando de download de arquivos
+func processGet(args []string) {
+	// identifica os flags informados
+	cmdGet := flag.NewFlagSet("get", flag.ExitOnError)
+	// define os parametros para sobrescrever o padrão configurado
+	pBucketFlags := addBucketFlags(cmdGet)
+	// define os parametros para utilização específicos para este método
+	pFilterFlags := addFilterFlags(cmdGet)
+	pRemove := cmdGet.Bool("rm", false, "remove files after transfer")
+	pRename := cmdGet.String("c", "", fmt.Sprintf("change the name of target file\n%s", renameVars))
+	pErrorNoFiles := cmdGet.Bool("enf", false, "terminate with exit code 1 if no files found")
+	pWorkers := cmdGet.Int("j", 1, "number of files downloaded in parallel")
+	pVerify := cmdGet.String("verify", "", "verify the downloaded file against object size, etag and stored checksum (md5, sha256, crc32c)")
+	pResume := cmdGet.Bool("resume", false, "keep the partial file of interrupted downloads and resume them from the missing bytes")
+	pLayout := cmdGet.String("layout", LayoutFlat, "layout of downloaded files in local folder (flat: all files in the same folder, tree: recreate the key path below bucket prefix as sub folders)")
+	pOutput := cmdGet.String("o", "", "write the content of the selected file to stdout (-)")
+	// processa os parametros
+	err := cmdGet.Parse(args)
+	if err != nil || len(args) == 0 {
+		cmdGet.Usage()
+		os.Exit(1)
+	}
+	// aplica os parametros de acesso ao bucket
+	pBucketFlags.apply()
 	// valida o filtro
-	if *pFilter == "" {
-		log.Fatalf("file name filter not provided")
+	filter, err := pFilterFlags.filter()
+	if err != nil {
+		log.Fatal(err)
 	}
 	// valida o rename
 	if *pRename == "" {
 		*pRename = "#FN#FE"
 	}
-	// ajusta o prefixo do bucket (sub pasta)
-	if *pBucketPrefix != "" {
-		*pBucketPrefix = strings.TrimPrefix(*pBucketPrefix, "/")
-		if !strings.HasSuffix(*pBucketPrefix, "/") {
-			*pBucketPrefix = *pBucketPrefix + "/"
+	err = filter.validateRename(*pRename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// valida a quantidade de recepções em paralelo
+	if *pWorkers < 1 {
+		log.Fatalf("number of parallel downloads {%d} is invalid", *pWorkers)
+	}
+	// valida a saída do conteúdo
+	if *pOutput != "" {
+		if *pOutput != stdStream {
+			log.Fatalf("output {%s} is invalid, only stdout (-) is supported", *pOutput)
+		}
+		if *pResume {
+			log.Fatalf("resume is not supported when writing to stdout")
 		}
 	}
-	// carrega das credenciais do vault se necessário
-	if myConfig.AccessKey == "" || myConfig.SecretKey == "" {
-		err = loadCredentials(*pRole)
-		if err != nil {
+	// valida a forma de gravação dos arquivos na pasta local
+	*pLayout = strings.ToLower(*pLayout)
+	if *pLayout != LayoutFlat && *pLayout != LayoutTree {
+		log.Fatalf("layout {%s} is invalid", *pLayout)
+	}
+	// valida o algoritmo de verificação
+	*pVerify = strings.ToLower(*pVerify)
+	if *pVerify != "" {
+		if _, err := newChecksum(*pVerify); err != nil {
 			log.Fatal(err)
 		}
 	}
 	// valida se há parametros suficientes
-	if *pFilter == "" || myConfig.Bucket == "" {
+	if myConfig.Bucket == "" {
 		cmdGet.Usage()
 		os.Exit(1)
 	}
 	// inicializa o serviço da aws
-	err = configureAWSClient()
+	err = connect(*pBucketFlags.Role)
 	if err != nil {
 		log.Fatal(err)
 	}
+	// grava o conteúdo na saída padrão
+	if *pOutput == stdStream {
+		err = receiveStream(filter, *pBucketFlags.Prefix, *pRemove, *pErrorNoFiles, *pVerify)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	// executa as recepções
-	err = receiveFiles(*pFilter, *pBucketPrefix, myConfig.LocalFolder, *pRename, *pRemove, *pErrorNoFiles)
+	err = receiveFiles(filter, *pBucketFlags.Prefix, myConfig.LocalFolder, *pRename, *pRemove, *pErrorNoFiles, *pWorkers, *pLayout, *pResume, *pVerify)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -499,108 +641,113 @@ func processPut(args []string) {
 	// identifica os flags informados
 	cmdPut := flag.NewFlagSet("put", flag.ExitOnError)
 	// define os parametros para sobrescrever o padrão configurado
-	pBucket := cmdPut.String("b", "", "bucket name")
-	pRegion := cmdPut.String("br", "", "bucket region")
-	pPartSize := cmdPut.Int("ps", 0, "size of each part of the file uploaded to the bucket (use 0 to automatic calculate)")
+	pBucketFlags := addBucketFlags(cmdPut)
 	pMetaData := cmdPut.String("m", "", "metadata that will be stored in the file uploaded to the bucket (sintax key1=value1;key2=value2...)")
-	pEndPoint := cmdPut.String("ep", "", "url of bucket end point (sintax https://my-s3-url.com)")
-	pFolder := cmdPut.String("df", "", "default folder for files")
 	// define os parametros para utilização específicos para este método
-	pFilter := cmdPut.String("f", "", "filter to select files")
+	pFilterFlags := addFilterFlags(cmdPut)
 	pRemove := cmdPut.Bool("rm", false, "remove files after transfer")
 	pRename := cmdPut.String("c", "", fmt.Sprintf("change the name of target file\n%s", renameVars))
 	pErrorNoFiles := cmdPut.Bool("enf", false, "terminate with exit code 1 if no files found")
-	pRole := cmdPut.String("r", "", "vault role name to access bucket")
-	// parametros adicionais
-	pBucketPrefix := cmdPut.String("bp", "", "bucket prefix (sub folder)")
-	pDebug := cmdPut.Bool("debug", false, "show additional information for debug")
+	pWorkers := cmdPut.Int("j", 1, "number of files uploaded in parallel")
+	pRecursive := cmdPut.Bool("R", false, "upload files of all sub folders keeping the relative path as part of the key")
+	pResume := cmdPut.Bool("resume", false, "keep a local checkpoint of multipart uploads and resume them from the missing parts")
+	pAbort := cmdPut.Bool("abort", false, "abort the multipart uploads with checkpoint of the selected files and remove the checkpoints")
+	pVerify := cmdPut.String("verify", "", "verify the uploaded object against file size and etag and store the file checksum in metadata (md5, sha256, crc32c)")
+	pTags := cmdPut.String("t", "", fmt.Sprintf("tags that will be stored in the file uploaded to the bucket, the values can use the variables of the target file name (sintax key1=value1;key2=value2...)\n%s", renameVars))
 	// processa os parametros
 	err := cmdPut.Parse(args)
 	if err != nil || len(args) == 0 {
 		cmdPut.Usage()
 		os.Exit(1)
 	}
-	// define o modo de debug
-	if *pDebug {
-		debug = true
-	}
-	// configura o bucket
-	if *pBucket != "" {
-		myConfig.Bucket = *pBucket
-	}
-	// configura a região do bucket
-	if *pRegion != "" {
-		myConfig.Region = *pRegion
-	}
-	// configura o tamanho das partes para o envio de arquivo multipart
-	if *pPartSize < 5*1024*1024 {
-		myConfig.PartSize = 0
-	} else {
-		myConfig.PartSize = *pPartSize
-	}
+	// aplica os parametros de acesso ao bucket
+	pBucketFlags.apply()
 	// configura os metadados que serão gravados por padrão em todos os
 	// arquivos que forem enviados para o bucket
 	if *pMetaData != "" {
-		// inicializa o mapa de metadados
-		myConfig.Metadata = make(map[string]string)
-		// extrai e configura os metados informados
-		// os metadados seguem o padrão: key1=valor1;key2=valor2
-		values := strings.Split(*pMetaData, ";")
-		for k, v := range values {
-			keyvalue := strings.Split(v, "=")
-			if len(keyvalue) < 2 {
-				log.Fatalf("[%d] metadata {%s} is invalid", k, v)
-			}
-			myConfig.Metadata[strings.TrimSpace(keyvalue[0])] = strings.TrimSpace(keyvalue[1])
+		myConfig.Metadata, err = parseMetadata(*pMetaData)
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
-	// configura o endereço http do endpoint para acesso ao bucket
-	if *pEndPoint != "" {
-		myConfig.EndPoint = *pEndPoint
-	}
-	// configura a pasta padrão onde estão ou serão gravados os arquivos
-	if *pFolder != "" {
-		myConfig.LocalFolder = *pFolder
+	// define as tags que serão gravadas nos arquivos enviados
+	var tags map[string]string
+	if *pTags != "" {
+		tags, err = parseTags(*pTags)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 	// valida o filtro
-	if *pFilter == "" {
-		log.Fatalf("file name filter not provided")
+	filter, err := pFilterFlags.filter()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// na leitura da entrada padrão o nome do objeto deve ser informado
+	if *pFilterFlags.Glob == stdStream {
+		if *pRename == "" {
+			log.Fatalf("target file name must be provided with -c when reading from stdin")
+		}
+		if *pRemove || *pResume || *pAbort || *pRecursive || *pWorkers != 1 {
+			log.Fatalf("flags -rm, -resume, -abort, -R and -j are not supported when reading from stdin")
+		}
+		if filter.restricted() {
+			log.Fatalf("exclude, age and size filters are not supported when reading from stdin")
+		}
 	}
 	// valida o rename
 	if *pRename == "" {
 		*pRename = "#FN#FE"
 	}
-	// ajusta o prefixo do bucket (sub pasta)
-	if *pBucketPrefix != "" {
-		*pBucketPrefix = strings.TrimPrefix(*pBucketPrefix, "/")
-		if !strings.HasSuffix(*pBucketPrefix, "/") {
-			*pBucketPrefix = *pBucketPrefix + "/"
-		}
+	err = filter.validateRename(*pRename)
+	if err != nil {
+		log.Fatal(err)
 	}
-	// carrega das credenciais do vault se necessário
-	if myConfig.AccessKey == "" || myConfig.SecretKey == "" {
-		err = loadCredentials(*pRole)
-		if err != nil {
+	// valida a quantidade de envios em paralelo
+	if *pWorkers < 1 {
+		log.Fatalf("number of parallel uploads {%d} is invalid", *pWorkers)
+	}
+	// valida o algoritmo de verificação
+	*pVerify = strings.ToLower(*pVerify)
+	if *pVerify != "" {
+		if _, err := newChecksum(*pVerify); err != nil {
 			log.Fatal(err)
 		}
 	}
 	// valida se há parametros suficientes
-	if *pFilter == "" || myConfig.Bucket == "" {
+	if myConfig.Bucket == "" {
 		cmdPut.Usage()
 		os.Exit(1)
 	}
 	// inicializa o serviço da aws
-	err = configureAWSClient()
+	err = connect(*pBucketFlags.Role)
 	if err != nil {
 		log.Fatal(err)
 	}
-	// executa as recepções
-	err = sendFiles(*pFilter, *pBucketPrefix, myConfig.LocalFolder, *pRename, *pRemove, myConfig.Metadata, *pErrorNoFiles)
+	// envia o conteúdo da entrada padrão
+	if *pFilterFlags.Glob == stdStream {
+		err = sendStream(*pBucketFlags.Prefix, *pRename, myConfig.Metadata, tags, *pVerify)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	// executa os envios
+	err = sendFiles(filter, *pBucketFlags.Prefix, myConfig.LocalFolder, *pRename, *pRemove, myConfig.Metadata, tags, *pErrorNoFiles, *pWorkers, *pRecursive, *pResume, *pAbort, *pVerify)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+// loga o endpoint e o bucket que será usado
+func logEndpoint() {
+	if myConfig.EndPoint != "" {
+		log.Printf("using custom endpoint {%s} for bucket {%s}...", myConfig.EndPoint, myConfig.Bucket)
+	} else {
+		log.Printf("using default AWS endpoint for bucket {%s}...", myConfig.Bucket)
+	}
+}
+
 // inicializa o serviço da aws com base nas configurações
 func configureAWSClient() (err error) {
 	// configura o transport do client http
@@ -612,6 +759,7 @@ func configureAWSClient() (err error) {
 		}).DialContext,
 		ForceAttemptHTTP2:     true,
 		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   100,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 5 * time.Second,
@@ -650,22 +798,19 @@ func configureAWSClient() (err error) {
 	}
 	// configura o serviço
 	s3client = s3.NewFromConfig(awsConfig)
+	s3region = awsConfig.Region
 	return nil
 }
 
 // Realiza o envio dos arquivos para o bucket com o filtro especificado
-func sendFiles(filter string, prefix string, folder string, rename string, remove bool, metaData map[string]string, errornofiles bool) error {
+func sendFiles(filter *fileFilter, prefix string, folder string, rename string, remove bool, metaData map[string]string, tags map[string]string, errornofiles bool, workers int, recursive bool, resume bool, abort bool, verify string) error {
 	// loga o endpoint e o bucket que será usado
-	if myConfig.EndPoint != "" {
-		log.Printf("using custom endpoint {%s} for bucket {%s}...", myConfig.EndPoint, myConfig.Bucket)
-	} else {
-		log.Printf("using default AWS endpoint for bucket {%s}...", myConfig.Bucket)
-	}
+	logEndpoint()
 	// ajusta os campos traduzindo as variaveis se utilizadas
 	prefix = parseName("", prefix)
-	filter = parseName("", filter)
+	filter.Glob = parseName("", filter.Glob)
 	// lista os arquivos que batem com o filtro
-	matches, err := filepath.Glob(filepath.Join(folder, filter))
+	matches, err := filter.files(folder, recursive)
 	if err != nil {
 		return fmt.Errorf("unable to list files with filter {%s}, %s", filter, err)
 	}
@@ -677,32 +822,53 @@ func sendFiles(filter string, prefix string, folder string, rename string, remov
 		}
 		return nil
 	}
-	// lista os arquivos
+	// lista os arquivos e define o nome do arquivo que sera gravado no bucket
+	items := make([]uploadItem, len(matches))
 	for k, v := range matches {
 		log.Printf("[%d] selected to upload: %s", k, v)
+		key := prefix + objectName(folder, v, filter.rename(rename, relativeName(folder, v, recursive)), recursive)
+		items[k] = uploadItem{Path: v, Key: key, Tagging: objectTagging(tags, key)}
+	}
+	// verifica se deve apenas cancelar os envios pendentes
+	if abort {
+		return abortUploads(items)
 	}
+	return uploadFiles(items, remove, metaData, workers, resume, verify)
+}
+
+// define um arquivo local e o nome do objeto no bucket para o envio
+type uploadItem struct {
+	Path string
+	Key  string
+	// tags do objeto no formato de query string
+	Tagging string
+}
+
+// Realiza o envio dos arquivos informados para o bucket
+func uploadFiles(items []uploadItem, remove bool, metaData map[string]string, workers int, resume bool, verify string) error {
+	// configura o uploader que será compartilhado por todos os envios
+	uploader := manager.NewUploader(s3client)
+	// define os resultados de cada envio
+	results := make([]transferResult, len(items))
 	// realiza o envio
-	for k, v := range matches {
+	started := time.Now()
+	runWorkers(len(items), workers, func(k int) {
+		v := items[k].Path
+		results[k].Name = v
 		// captura o horário de início da transmissão
 		start := time.Now()
-		// define o nome do arquivo que sera gravado no bucket
-		fileName := prefix + parseName(v, rename)
 		// realiza o envio
 		log.Printf("[%d] starting upload of file {%s}...", k, v)
-		n, result, err := send(v, fileName, metaData)
+		n, result, err := send(uploader, v, items[k].Key, metaData, items[k].Tagging, resume, verify)
 		if err != nil {
-			log.Fatalf("[%d] failed to upload file {%s}, %s", k, v, err)
+			results[k].Err = err
+			log.Printf("[%d] failed to upload file {%s}, %s", k, v, err)
+			return
 		}
+		results[k].Size = n
 		// calcula a taxa de envio do arquivo
 		elapsed := time.Since(start).Seconds()
-		var rate float64
-		if elapsed <= 0 {
-			rate = float64(n)
-		} else {
-			rate = float64(n) / float64(elapsed)
-		}
-		rate /= 1024 * 1024
-		log.Printf("[%d] upload completed, size: %d elapsed: %.2fs rate: %.2fMB/s url: %s", k, n, elapsed, rate, result.Location)
+		log.Printf("[%d] upload completed, size: %d elapsed: %.2fs rate: %.2fMB/s url: %s", k, n, elapsed, transferRate(n, elapsed), result.Location)
 		// verifica se deve remover o arquivo
 		if remove {
 			err = os.Remove(v)
@@ -712,12 +878,99 @@ func sendFiles(filter string, prefix string, folder string, rename string, remov
 				log.Printf("[%d] file {%s} removed successfully", k, v)
 			}
 		}
+	})
+	// exibe o resumo dos envios
+	failed := logSummary("upload", results, time.Since(started).Seconds())
+	if failed > 0 {
+		return fmt.Errorf("failed to upload %d of %d files", failed, len(results))
 	}
 	return nil
 }
 
+// lista os arquivos da pasta que batem com o filtro, no modo recursivo
+// todas as sub pastas são verificadas e o filtro é aplicado ao caminho
+// relativo do arquivo quando possuir separador de pasta, caso contrário
+// é aplicado apenas ao nome do arquivo
+func listFiles(folder string, filter string, recursive bool) (files []string, err error) {
+	if !recursive {
+		matches, err := filepath.Glob(filepath.Join(folder, filter))
+		if err != nil {
+			return nil, err
+		}
+		// descarta as pastas que batem com o filtro
+		for _, v := range matches {
+			stat, err := os.Stat(v)
+			if err != nil {
+				return nil, err
+			}
+			if stat.Mode().IsRegular() {
+				files = append(files, v)
+			}
+		}
+		return files, nil
+	}
+	// valida o filtro antes de percorrer as pastas
+	match, err := relativeMatcher(filepath.ToSlash(filter))
+	if err != nil {
+		return nil, err
+	}
+	err = filepath.WalkDir(folder, func(file string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(folder, file)
+		if err != nil {
+			return err
+		}
+		if match(filepath.ToSlash(rel)) {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// cria a função que verifica se o caminho relativo atende ao filtro, o
+// filtro é aplicado ao caminho completo quando possuir separador de pasta,
+// caso contrário é aplicado apenas ao nome do arquivo
+func relativeMatcher(filter string) (func(rel string) bool, error) {
+	pattern, err := globPattern(filter)
+	if err != nil {
+		return nil, err
+	}
+	base := !strings.Contains(filter, "/")
+	return func(rel string) bool {
+		if base {
+			rel = path.Base(rel)
+		}
+		return pattern.MatchString(rel)
+	}, nil
+}
+
+// define o nome do objeto no bucket para o arquivo local, no modo
+// recursivo o caminho relativo à pasta é mantido e o renomeio é
+// aplicado apenas ao nome do arquivo
+func objectName(folder string, file string, rename string, recursive bool) string {
+	name := parseName(file, rename)
+	if !recursive {
+		return name
+	}
+	rel, err := filepath.Rel(folder, file)
+	if err != nil {
+		return name
+	}
+	dir := filepath.ToSlash(filepath.Dir(rel))
+	if dir == "." {
+		return name
+	}
+	return dir + "/" + name
+}
+
 // realiza o envio dos arquivos com o filtro especificado para o bucket
-func send(file string, key string, metaData map[string]string) (n int64, result *manager.UploadOutput, err error) {
+func send(uploader *manager.Uploader, file string, key string, metaData map[string]string, tagging string, resume bool, verify string) (n int64, result *manager.UploadOutput, err error) {
 	// abre o arquivo para realizar o envio
 	f, err := os.OpenFile(file, os.O_RDONLY, 0774)
 	if err != nil {
@@ -730,182 +983,605 @@ func send(file string, key string, metaData map[string]string) (n int64, result
 		return 0, nil, fmt.Errorf("unable to read properties of file {%s}, %s", file, err)
 	}
 	// calcula o tamanho da parte se necessário
-	partSize := myConfig.PartSize
-	if partSize < 1024*1024*5 {
-		if stat.Size() < 1024*1024*1024*10 {
-			partSize = 1024 * 1024 * 64
-		} else if stat.Size() < 1024*1024*1024*100 {
-			partSize = 1024 * 1024 * 100
-		} else {
-			partSize = 1024 * 1024 * 250
+	partSize := clampPartSize(stat.Size(), partSizeFor(stat.Size()))
+	// o envio com checkpoint é realizado apenas quando for multipart, as
+	// partes são enviadas em paralelo e o md5 de cada parte é verificado
+	var sum, etag string
+	if resume && stat.Size() > partSize {
+		etag, result, err = sendResumable(f, stat, key, metaData, tagging, partSize, verify != "")
+		if err != nil {
+			return 0, nil, fmt.Errorf("transfer failed, %s", err)
+		}
+	} else {
+		// calcula o checksum e o ETag enquanto o arquivo é lido para o
+		// envio, neste caso o arquivo é lido em sequência
+		var body io.Reader = f
+		var h *contentHash
+		if verify != "" {
+			h, err = newContentHash(verify, partSize)
+			if err != nil {
+				return 0, nil, err
+			}
+			body = io.TeeReader(f, h)
+		}
+		result, err = sendObject(uploader, body, key, metaData, tagging, partSize)
+		if err != nil {
+			return 0, nil, fmt.Errorf("transfer failed, %s", err)
+		}
+		if h != nil {
+			sum, etag = h.Sum(), h.ETag()
 		}
 	}
-	// configura o uploader
-	uploader := manager.NewUploader(s3client, func(u *manager.Uploader) {
-		u.PartSize = int64(partSize)
-	})
-	// realiza o envio
-	result, err = uploader.Upload(context.TODO(), &s3.PutObjectInput{
+	// verifica a integridade do objeto gravado e grava o checksum
+	if verify != "" {
+		err = verifyUpload(key, stat.Size(), sum, etag, verify)
+		if err != nil {
+			return 0, nil, err
+		}
+		if sum != "" {
+			err = storeChecksum(key, metaData, verify, sum)
+			if err != nil {
+				return 0, nil, err
+			}
+		}
+	}
+	return stat.Size(), result, nil
+}
+
+// realiza o envio do arquivo usando o uploader
+func sendObject(uploader *manager.Uploader, body io.Reader, key string, metaData map[string]string, tagging string, partSize int64) (*manager.UploadOutput, error) {
+	// realiza o envio, o tamanho da parte é definido apenas para este
+	// arquivo pois o uploader é compartilhado
+	input := &s3.PutObjectInput{
 		Bucket:   aws.String(myConfig.Bucket),
 		Key:      aws.String(key),
-		Body:     f,
+		Body:     body,
 		Metadata: metaData,
+	}
+	if tagging != "" {
+		input.Tagging = aws.String(tagging)
+	}
+	return uploader.Upload(context.TODO(), input, func(u *manager.Uploader) {
+		u.PartSize = partSize
 	})
-	if err != nil {
-		return 0, nil, fmt.Errorf("transfer failed, %s", err)
+}
+
+// calcula o tamanho da parte do envio multipart para o tamanho do
+// arquivo, caso não tenha sido configurado
+func partSizeFor(size int64) int64 {
+	partSize := int64(myConfig.PartSize)
+	if partSize < 1024*1024*5 {
+		if size < 1024*1024*1024*10 {
+			partSize = 1024 * 1024 * 64
+		} else if size < 1024*1024*1024*100 {
+			partSize = 1024 * 1024 * 100
+		} else {
+			partSize = 1024 * 1024 * 250
+		}
 	}
-	return stat.Size(), result, nil
+	return partSize
+}
+
+// retorna o tamanho das partes do envio multipart, o tamanho informado é
+// aumentado quando o arquivo precisar de mais partes que o limite do s3
+func clampPartSize(size int64, partSize int64) int64 {
+	min := size/int64(manager.MaxUploadParts) + 1
+	if partSize < min {
+		return min
+	}
+	return partSize
 }
 
 // Recebe todos os arquivos que atendem ao filtro especificado
-func receiveFiles(filter string, prefix string, folder string, rename string, remove bool, errornofiles bool) error {
-	// define uma váriavel para usar para armazenar os arquivos que serão baixados
-	var matches []types.Object
-	// define um contador para exibir quantos objetos foram verificados no bucket
-	var count int64
+func receiveFiles(filter *fileFilter, prefix string, folder string, rename string, remove bool, errornofiles bool, workers int, layout string, resume bool, verify string) error {
 	// loga o endpoint e o bucket que será usado
-	if myConfig.EndPoint != "" {
-		log.Printf("using custom endpoint {%s} for bucket {%s}...", myConfig.EndPoint, myConfig.Bucket)
-	} else {
-		log.Printf("using default AWS endpoint for bucket {%s}...", myConfig.Bucket)
-	}
+	logEndpoint()
 	// ajusta os campos traduzindo as variaveis se utilizadas
 	prefix = parseName("", prefix)
-	filter = parseName("", filter)
-	// se foi passado wildcard no filto então lista o bucket para selecionar os arquivos
-	if strings.Contains(filter, "*") {
-		// define os parametros de listagem
-		params := &s3.ListObjectsV2Input{
-			Bucket: aws.String(myConfig.Bucket),
-			Prefix: aws.String(prefix),
-		}
-		// define o paginador
-		paginator := s3.NewListObjectsV2Paginator(s3client, params, func(o *s3.ListObjectsV2PaginatorOptions) {
-			o.Limit = 1000
-		})
-		// define a expressão regular para realizar a pesquisa
-		// caso seja passado o prefixo do bucket o mesmo deve
-		// ser considerado na validação
-		pattern := wildCardToRegexp(filter)
-		if prefix != "" {
-			pattern = regexp.QuoteMeta(prefix) + pattern
-		}
-		// processa a listagem das páginas
-		for paginator.HasMorePages() {
-			output, err := paginator.NextPage(context.TODO())
-			if err != nil {
-				return fmt.Errorf("unable to list bucket, %s", err)
-			}
-			for _, value := range output.Contents {
-				count++
-				match, err := regexp.MatchString(pattern, *value.Key)
-				if err != nil {
-					return fmt.Errorf("unable filter files, %s", err)
-				}
-				if match {
-					matches = append(matches, value)
-				}
-			}
-		}
-		// exibe a quantidade de objetos lidos do bucket
-		log.Printf("total of keys verified in bucket {%s}: %d", myConfig.Bucket, count)
-		// verifica se foi selecionado algum arquivo
-		if len(matches) == 0 {
-			log.Printf("no files found in bucket {%s} with filter {%s}", myConfig.Bucket, filter)
-			if errornofiles {
-				os.Exit(1)
-			}
-			return nil
+	filter.Glob = parseName("", filter.Glob)
+	// seleciona os objetos do bucket que atendem ao filtro
+	matches, err := filter.objects(prefix)
+	if err != nil {
+		return err
+	}
+	// verifica se foi selecionado algum arquivo
+	if len(matches) == 0 {
+		log.Printf("no files found in bucket {%s} with filter {%s}", myConfig.Bucket, filter)
+		if errornofiles {
+			os.Exit(1)
 		}
-	} else {
-		// adiciona o proprio filtro para buscar no bucket
-		matches = append(matches, types.Object{
-			Key: aws.String(prefix + filter),
-		})
+		return nil
 	}
 	// lista os arquivos
 	for k, v := range matches {
 		log.Printf("[%d] selected to download: %s", k, *v.Key)
 	}
-	// realiza a recepção
+	// define o nome dos arquivos que serão recebidos antes de iniciar as
+	// recepções em paralelo, na gravação sem sub pastas alerta quando mais
+	// de um objeto gerar o mesmo arquivo
+	items := make([]downloadItem, len(matches))
+	targets := make(map[string]int)
 	for k, v := range matches {
+		items[k].Key = *v.Key
+		items[k].Path, items[k].Err = localPath(folder, prefix, *v.Key, filter.rename(rename, strings.TrimPrefix(*v.Key, prefix)), layout)
+		if items[k].Err != nil {
+			continue
+		}
+		if i, ok := targets[items[k].Path]; ok {
+			log.Printf("[%d] file {%s} will overwrite the file of key {%s} at {%s}", k, *v.Key, *matches[i].Key, items[k].Path)
+		}
+		targets[items[k].Path] = k
+	}
+	return downloadFiles(items, remove, workers, resume, verify)
+}
+
+// define um objeto do bucket e o arquivo local para a recepção
+type downloadItem struct {
+	Key  string
+	Path string
+	// erro ocorrido ao definir o arquivo local
+	Err error
+}
+
+// Realiza a recepção dos objetos informados do bucket
+func downloadFiles(items []downloadItem, remove bool, workers int, resume bool, verify string) error {
+	// define os resultados de cada recepção
+	results := make([]transferResult, len(items))
+	// configura o downloader que será compartilhado por todas as recepções
+	downloader := manager.NewDownloader(s3client, func(d *manager.Downloader) {
+		d.PartSize = 64 * 1024 * 1024
+	})
+	// realiza a recepção
+	started := time.Now()
+	runWorkers(len(items), workers, func(k int) {
+		v := items[k]
+		results[k].Name = v.Key
+		// verifica se foi possível definir o nome do arquivo
+		if v.Err != nil {
+			results[k].Err = v.Err
+			log.Printf("[%d] failed to download file {%s}, %s", k, v.Key, v.Err)
+			return
+		}
 		// captura o horário de início da transmissão
 		start := time.Now()
-		// define o nome do arquivo que sera recebido
-		filePath := filepath.Join(folder, parseName(*v.Key, rename))
+		// cria a sub pasta do arquivo se necessário
+		filePath := v.Path
+		if err := os.MkdirAll(filepath.Dir(filePath), 0774); err != nil {
+			results[k].Err = fmt.Errorf("unable to create folder, %s", err)
+			log.Printf("[%d] failed to download file {%s}, %s", k, v.Key, results[k].Err)
+			return
+		}
 		// realiza a recepção
-		log.Printf("[%d] starting download of file {%s}...", k, *v.Key)
-		n, err := receive(*v.Key, filePath)
+		log.Printf("[%d] starting download of file {%s}...", k, v.Key)
+		var n int64
+		var err error
+		if resume {
+			n, err = receiveResumable(v.Key, filePath, verify)
+		} else {
+			n, err = receive(downloader, v.Key, filePath, verify)
+		}
 		if err != nil {
-			log.Fatalf("[%d] failed to download file {%s}, %s", k, *v.Key, err)
+			results[k].Err = err
+			log.Printf("[%d] failed to download file {%s}, %s", k, v.Key, err)
+			return
 		}
+		results[k].Size = n
 		// calcula a taxa de recepção do arquivo
 		elapsed := time.Since(start).Seconds()
-		var rate float64
-		if elapsed <= 0 {
-			rate = float64(n)
-		} else {
-			rate = float64(n) / float64(elapsed)
-		}
-		rate /= 1024 * 1024
-		log.Printf("[%d] download completed, size: %dbytes elapsed: %.2fs rate: %.2fMB/s path: %s", k, n, elapsed, rate, filePath)
-		// verifica se deve remover o arquivo
+		log.Printf("[%d] download completed, size: %dbytes elapsed: %.2fs rate: %.2fMB/s path: %s", k, n, elapsed, transferRate(n, elapsed), filePath)
+		// verifica se deve remover o arquivo, apenas após a sua
+		// própria recepção ter sido concluída com sucesso
 		if remove {
 			_, err := s3client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
 				Bucket: aws.String(myConfig.Bucket),
-				Key:    aws.String(*v.Key),
+				Key:    aws.String(v.Key),
 			})
 			if err != nil {
-				log.Printf("[%d] unable to remove file {%s}, %s", k, *v.Key, err)
+				log.Printf("[%d] unable to remove file {%s}, %s", k, v.Key, err)
 			} else {
-				log.Printf("[%d] file {%s} removed successfully", k, *v.Key)
+				log.Printf("[%d] file {%s} removed successfully", k, v.Key)
 			}
 		}
+	})
+	// exibe o resumo das recepções
+	failed := logSummary("download", results, time.Since(started).Seconds())
+	if failed > 0 {
+		return fmt.Errorf("failed to download %d of %d files", failed, len(results))
 	}
 	return nil
 }
 
-// realiza a recepção do arquivo
-func receive(key string, filePath string) (n int64, err error) {
-	// cria o arquivo em disco
-	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0774)
+// seleciona os objetos do bucket que atendem ao filtro, se o filtro não
+// possuir wildcard a própria chave é selecionada sem listar o bucket
+func selectObjects(filter string, prefix string) (matches []types.Object, err error) {
+	if !hasWildcard(filter) {
+		return []types.Object{{Key: aws.String(prefix + filter)}}, nil
+	}
+	// define a expressão regular para realizar a pesquisa
+	pattern, err := keyPattern(prefix, filter)
 	if err != nil {
-		return 0, fmt.Errorf("unable to create file, %s", err)
+		return nil, fmt.Errorf("unable filter files, %s", err)
 	}
-	defer f.Close()
-	// configura o downloader
-	downloader := manager.NewDownloader(s3client, func(d *manager.Downloader) {
-		d.PartSize = 64 * 1024 * 1024
+	matches, count, err := selectMatching(prefix, func(obj types.Object) bool {
+		return pattern.MatchString(*obj.Key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// exibe a quantidade de objetos lidos do bucket
+	log.Printf("total of keys verified in bucket {%s}: %d", myConfig.Bucket, count)
+	return matches, nil
+}
+
+// verifica se a chave existe no bucket, a listagem é usada no lugar da
+// leitura das propriedades para exigir apenas a permissão de listagem
+func keyExists(key string) (bool, error) {
+	output, err := s3client.ListObjectsV2(context.TODO(), &s3.ListObjectsV2Input{
+		Bucket:  aws.String(myConfig.Bucket),
+		Prefix:  aws.String(key),
+		MaxKeys: 1,
 	})
-	// inicia o download
-	n, err = downloader.Download(context.TODO(), f, &s3.GetObjectInput{
+	if err != nil {
+		return false, fmt.Errorf("unable to list bucket, %s", err)
+	}
+	return len(output.Contents) > 0 && aws.ToString(output.Contents[0].Key) == key, nil
+}
+
+// verifica se o erro indica que o objeto não existe no bucket
+func isNotFound(err error) bool {
+	var re *awshttp.ResponseError
+	return errors.As(err, &re) && re.HTTPStatusCode() == http.StatusNotFound
+}
+
+// seleciona os objetos do bucket abaixo do prefixo aceitos pela função
+// informada e retorna também a quantidade de objetos lidos
+func selectMatching(prefix string, match func(obj types.Object) bool) (matches []types.Object, count int64, err error) {
+	// processa a listagem das páginas
+	count, err = listObjects(prefix, func(value types.Object) error {
+		// desconsidera os objetos que representam pastas
+		if strings.HasSuffix(*value.Key, "/") {
+			return nil
+		}
+		if match(value) {
+			matches = append(matches, value)
+		}
+		return nil
+	})
+	return matches, count, err
+}
+
+// define a expressão regular para selecionar as chaves do bucket com o
+// filtro, caso seja passado o prefixo do bucket o mesmo deve ser
+// considerado na validação
+func keyPattern(prefix string, filter string) (*regexp.Regexp, error) {
+	if err := validateGlob(filter); err != nil {
+		return nil, err
+	}
+	return regexp.Compile("^" + regexp.QuoteMeta(prefix) + wildCardToRegexp(filter) + "$")
+}
+
+// lista todos os objetos do bucket com o prefixo informado executando a
+// função para cada objeto, retorna a quantidade de objetos verificados
+func listObjects(prefix string, fn func(obj types.Object) error) (count int64, err error) {
+	// define os parametros de listagem
+	params := &s3.ListObjectsV2Input{
+		Bucket: aws.String(myConfig.Bucket),
+		Prefix: aws.String(prefix),
+	}
+	// define o paginador
+	paginator := s3.NewListObjectsV2Paginator(s3client, params, func(o *s3.ListObjectsV2PaginatorOptions) {
+		o.Limit = 1000
+	})
+	// processa a listagem das páginas
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return count, fmt.Errorf("unable to list bucket, %s", err)
+		}
+		for _, value := range output.Contents {
+			count++
+			err = fn(value)
+			if err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, nil
+}
+
+// remove os objetos do bucket em lotes de até 1000 chaves, retorna o
+// erro de cada chave que não pode ser removida
+func deleteObjects(keys []string) map[string]error {
+	failures := make(map[string]error)
+	for i := 0; i < len(keys); i += 1000 {
+		// define o lote de chaves
+		batch := keys[i:]
+		if len(batch) > 1000 {
+			batch = batch[:1000]
+		}
+		objects := make([]types.ObjectIdentifier, len(batch))
+		for k, v := range batch {
+			objects[k] = types.ObjectIdentifier{Key: aws.String(v)}
+		}
+		// remove o lote, no modo silencioso apenas os erros são retornados
+		output, err := s3client.DeleteObjects(context.TODO(), &s3.DeleteObjectsInput{
+			Bucket: aws.String(myConfig.Bucket),
+			Delete: &types.Delete{
+				Objects: objects,
+				Quiet:   true,
+			},
+		})
+		if err != nil {
+			for _, v := range batch {
+				failures[v] = err
+			}
+			continue
+		}
+		for _, v := range output.Errors {
+			failures[aws.ToString(v.Key)] = fmt.Errorf("%s, %s", aws.ToString(v.Code), aws.ToString(v.Message))
+		}
+	}
+	return failures
+}
+
+// define o caminho local do arquivo para o objeto, na gravação em
+// sub pastas o caminho da chave abaixo do prefixo é recriado na pasta
+// local e o renomeio é aplicado apenas ao nome do arquivo
+func localPath(folder string, prefix string, key string, rename string, layout string) (string, error) {
+	name := parseName(key, rename)
+	if name == "" {
+		return "", fmt.Errorf("unable to define file name for key {%s}", key)
+	}
+	filePath := filepath.Join(folder, filepath.FromSlash(name))
+	if layout == LayoutTree {
+		dir := path.Dir(strings.TrimPrefix(key, prefix))
+		filePath = filepath.Join(folder, filepath.FromSlash(dir), filepath.FromSlash(name))
+	}
+	// garante que o arquivo não será gravado fora da pasta local, o nome
+	// pode conter pastas capturadas da chave no renomeio
+	rel, err := filepath.Rel(folder, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("key {%s} resolves outside of folder {%s}", key, folder)
+	}
+	return filePath, nil
+}
+
+// realiza a recepção do arquivo, o arquivo é gravado com um nome
+// temporário na mesma pasta e renomeado apenas após a recepção ser
+// concluída para não expor arquivos incompletos
+func receive(downloader *manager.Downloader, key string, filePath string, verify string) (n int64, err error) {
+	// identifica o tamanho e o checksum do objeto para verificação
+	var head *s3.HeadObjectOutput
+	if verify != "" {
+		head, err = s3client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+			Bucket: aws.String(myConfig.Bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("unable to read properties of object, %s", err)
+		}
+	}
+	// cria o arquivo temporário em disco
+	f, err := createTempFile(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("unable to create file, %s", err)
+	}
+	// remove o arquivo temporário em caso de falha
+	defer func() {
+		f.Close()
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+	// inicia o download, na verificação garante que todas as partes
+	// sejam da mesma versão do objeto
+	input := &s3.GetObjectInput{
 		Bucket: aws.String(myConfig.Bucket),
 		Key:    aws.String(key),
+	}
+	// na verificação as partes são recebidas em sequência para calcular
+	// o checksum enquanto o arquivo é gravado
+	var w io.WriterAt = f
+	var h *contentHash
+	concurrency := downloader.Concurrency
+	if head != nil {
+		input.IfMatch = head.ETag
+		h, err = newContentHash(verify, etagPartSize(strings.Trim(aws.ToString(head.ETag), `"`), head.ContentLength))
+		if err != nil {
+			return 0, err
+		}
+		w = &hashWriterAt{w: f, hash: h}
+		concurrency = 1
+	}
+	n, err = downloader.Download(context.TODO(), w, input, func(d *manager.Downloader) {
+		d.Concurrency = concurrency
 	})
 	if err != nil {
 		return 0, fmt.Errorf("unable to download file, %s", err)
 	}
-	return
+	// garante que o conteúdo foi gravado em disco antes de renomear
+	err = f.Sync()
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to write file, %s", err)
+	}
+	// verifica a integridade do arquivo recebido
+	if head != nil {
+		err = verifyDownload(filePath, n, head, h)
+		if err != nil {
+			return 0, err
+		}
+	}
+	// move o arquivo temporário para o nome definitivo
+	err = os.Rename(f.Name(), filePath)
+	if err != nil {
+		return 0, fmt.Errorf("unable to rename temporary file, %s", err)
+	}
+	return n, nil
+}
+
+// cria um arquivo temporário com nome único na pasta do arquivo informado
+func createTempFile(filePath string) (*os.File, error) {
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("%s.%d.s3tmp", filePath, time.Now().UnixNano())
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0774)
+		if err != nil && os.IsExist(err) && i < 10 {
+			continue
+		}
+		return f, err
+	}
+}
+
+// define o resultado de uma transferência
+type transferResult struct {
+	// nome do arquivo ou objeto transferido
+	Name string
+	// quantidade de bytes transferidos
+	Size int64
+	// erro ocorrido na transferência
+	Err error
+}
+
+// executa a função informada para cada item usando no máximo a
+// quantidade de workers informada em paralelo
+func runWorkers(total int, workers int, fn func(k int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > total {
+		workers = total
+	}
+	// distribui os itens entre os workers
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range jobs {
+				fn(k)
+			}
+		}()
+	}
+	for k := 0; k < total; k++ {
+		jobs <- k
+	}
+	close(jobs)
+	wg.Wait()
 }
 
-// converte uma expressão wildcard para regex
+// calcula a taxa de transferência em MB/s
+func transferRate(n int64, elapsed float64) float64 {
+	var rate float64
+	if elapsed <= 0 {
+		rate = float64(n)
+	} else {
+		rate = float64(n) / elapsed
+	}
+	return rate / (1024 * 1024)
+}
+
+// exibe o resumo das transferências e retorna a quantidade de falhas
+func logSummary(operation string, results []transferResult, elapsed float64) (failed int) {
+	var size int64
+	for k, v := range results {
+		if v.Err != nil {
+			failed++
+			log.Printf("[%d] %s of {%s} failed, %s", k, operation, v.Name, v.Err)
+			continue
+		}
+		size += v.Size
+	}
+	log.Printf("%s summary, files: %d succeeded: %d failed: %d size: %d elapsed: %.2fs rate: %.2fMB/s", operation, len(results), len(results)-failed, failed, size, elapsed, transferRate(size, elapsed))
+	return failed
+}
+
+// converte uma expressão wildcard para regex com a mesma sintaxe do
+// filepath.Glob, onde "*" e "?" não atravessam as pastas, "[a-z]" define
+// uma classe de caracteres e "\" escapa o caractere seguinte. O "**"
+// atravessa as pastas e "**/" também aceita nenhuma pasta
 func wildCardToRegexp(pattern string) string {
 	var result strings.Builder
-	var lastRune rune
-	for _, v := range pattern {
-		if v == '*' {
-			if lastRune != v {
-				lastRune = v
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			j := i
+			for j < len(pattern) && pattern[j] == '*' {
+				j++
+			}
+			switch {
+			case j-i == 1:
+				result.WriteString("[^/]*")
+			case j < len(pattern) && pattern[j] == '/':
+				result.WriteString("(.*/)?")
+				j++
+			default:
 				result.WriteString(".*")
 			}
-			continue
+			i = j - 1
+		case '?':
+			result.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			result.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			// a classe sem o fechamento é mantida para que a expressão
+			// regular seja rejeitada
+			result.WriteByte('[')
+			if i+1 < len(pattern) && pattern[i+1] == '^' {
+				result.WriteString("^/")
+				i++
+			}
+			for i+1 < len(pattern) && pattern[i+1] != ']' {
+				i++
+				switch pattern[i] {
+				case '\\':
+					if i+1 < len(pattern) {
+						i++
+					}
+					result.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+				case '[':
+					result.WriteString(`\[`)
+				default:
+					result.WriteByte(pattern[i])
+				}
+			}
+			if i+1 < len(pattern) {
+				i++
+				result.WriteByte(']')
+			}
+		default:
+			result.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
 		}
-		result.WriteRune(v)
-		lastRune = v
 	}
 	return result.String()
 }
 
+// valida a sintaxe do filtro com as mesmas regras do filepath.Glob
+func validateGlob(filter string) error {
+	if _, err := path.Match(filter, ""); err != nil {
+		return fmt.Errorf("filter {%s} is invalid, %s", filter, err)
+	}
+	return nil
+}
+
+// verifica se o filtro possui caracteres especiais do wildcard
+func hasWildcard(filter string) bool {
+	return strings.ContainsAny(filter, `*?[\`)
+}
+
+// cria a expressão regular que valida o nome completo com o filtro
+func globPattern(filter string) (*regexp.Regexp, error) {
+	if err := validateGlob(filter); err != nil {
+		return nil, err
+	}
+	return regexp.Compile("^" + wildCardToRegexp(filter) + "$")
+}
+
 // converte um nome em outro usando a máscara informada
 func parseName(name string, mask string) string {
 	// define os valores das datas
@@ -919,9 +1595,11 @@ func parseName(name string, mask string) string {
 	second := date.Format("05")
 	milisecond := date.Format("000")
 	timestamp := strings.ReplaceAll(date.Format("20060102150405.999999999"), ".", "")
+	randomMutex.Lock()
 	random1 := random.Intn(9)
 	random2 := random.Intn(99)
 	random4 := random.Intn(9999)
+	randomMutex.Unlock()
 	// extrai apenas o nome do arquivo
 	_, name = filepath.Split(name)
 	// extrai o nome e a extenção do arquivo
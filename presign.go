@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Define os tipos de url pré assinadas
+const (
+	PresignGet  = "get"
+	PresignPut  = "put"
+	PresignPost = "post"
+)
+
+// define o algoritmo de assinatura das políticas de envio
+const signatureAlgorithm = "AWS4-HMAC-SHA256"
+
+// define o formulário para envio de arquivos com a política assinada
+type presignedPost struct {
+	URL    string            `json:"url"`
+	Fields map[string]string `json:"fields"`
+}
+
+// processa o comando de geração de urls pré assinadas
+func processPresign(args []string) {
+	// identifica os flags informados
+	cmdPresign := flag.NewFlagSet("presign", flag.ExitOnError)
+	// define os parametros para sobrescrever o padrão configurado
+	pBucketFlags := addBucketFlags(cmdPresign)
+	// define os parametros para utilização específicos para este método
+	pMethod := cmdPresign.String("method", PresignGet, "type of presigned url (get: download the file, put: upload the file, post: form to upload files with the key starting with the file name)")
+	pKey := cmdPresign.String("k", "", fmt.Sprintf("name of the file in bucket prefix, in post is the start of the name of the files\n%s", renameVars))
+	pExpires := cmdPresign.Duration("e", time.Hour, "time until the url expires (max 168h)")
+	pMinSize := cmdPresign.Int64("minsize", 0, "minimum size in bytes of the uploaded file (post only)")
+	pMaxSize := cmdPresign.Int64("maxsize", 0, "maximum size in bytes of the uploaded file (post only)")
+	// processa os parametros
+	err := cmdPresign.Parse(args)
+	if err != nil || len(args) == 0 {
+		cmdPresign.Usage()
+		os.Exit(1)
+	}
+	// aplica os parametros de acesso ao bucket
+	pBucketFlags.apply()
+	// valida o tipo de url
+	*pMethod = strings.ToLower(*pMethod)
+	if *pMethod != PresignGet && *pMethod != PresignPut && *pMethod != PresignPost {
+		log.Fatalf("presign method {%s} is invalid", *pMethod)
+	}
+	// valida o nome do arquivo
+	if *pKey == "" && *pMethod != PresignPost {
+		log.Fatalf("file name not provided")
+	}
+	// valida o tempo de expiração, limitado a 7 dias pela assinatura
+	if *pExpires <= 0 || *pExpires > 7*24*time.Hour {
+		log.Fatalf("expiration {%s} is invalid", *pExpires)
+	}
+	// valida os limites de tamanho
+	if *pMinSize < 0 || *pMaxSize < 0 || (*pMaxSize > 0 && *pMinSize > *pMaxSize) {
+		log.Fatalf("size range {%d-%d} is invalid", *pMinSize, *pMaxSize)
+	}
+	// valida se há parametros suficientes
+	if myConfig.Bucket == "" {
+		cmdPresign.Usage()
+		os.Exit(1)
+	}
+	// inicializa o serviço da aws
+	err = connect(*pBucketFlags.Role)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// gera a url
+	key := parseName("", *pBucketFlags.Prefix) + parseName("", *pKey)
+	if *pMethod == PresignPost {
+		err = presignPost(key, *pExpires, *pMinSize, *pMaxSize)
+	} else {
+		err = presignURL(*pMethod, key, *pExpires)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// gera a url pré assinada para receber ou enviar o arquivo
+func presignURL(method string, key string, expires time.Duration) error {
+	// loga o endpoint e o bucket que será usado
+	logEndpoint()
+	client := s3.NewPresignClient(s3client, s3.WithPresignExpires(expires))
+	var url string
+	switch method {
+	case PresignGet:
+		request, err := client.PresignGetObject(context.TODO(), &s3.GetObjectInput{
+			Bucket: aws.String(myConfig.Bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to presign url, %s", err)
+		}
+		url = request.URL
+	case PresignPut:
+		request, err := client.PresignPutObject(context.TODO(), &s3.PutObjectInput{
+			Bucket: aws.String(myConfig.Bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to presign url, %s", err)
+		}
+		url = request.URL
+	}
+	log.Printf("presigned %s url for file {%s} expires at %s", method, key, time.Now().Add(expires).UTC().Format(time.RFC3339))
+	fmt.Println(url)
+	return nil
+}
+
+// gera o formulário com a política assinada para envio de arquivos
+// com o nome iniciando pelo prefixo informado
+func presignPost(prefix string, expires time.Duration, minSize int64, maxSize int64) error {
+	// loga o endpoint e o bucket que será usado
+	logEndpoint()
+	if s3region == "" {
+		return fmt.Errorf("bucket region not provided")
+	}
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	credential := fmt.Sprintf("%s/%s/%s/s3/aws4_request", myConfig.AccessKey, date, s3region)
+	fields := map[string]string{
+		"key":              prefix + "${filename}",
+		"x-amz-algorithm":  signatureAlgorithm,
+		"x-amz-credential": credential,
+		"x-amz-date":       now.Format("20060102T150405Z"),
+	}
+	if myConfig.AccessToken != "" {
+		fields["x-amz-security-token"] = myConfig.AccessToken
+	}
+	// define as condições da política
+	conditions := []interface{}{
+		map[string]string{"bucket": myConfig.Bucket},
+		[]string{"starts-with", "$key", prefix},
+	}
+	for k, v := range fields {
+		if k != "key" {
+			conditions = append(conditions, map[string]string{k: v})
+		}
+	}
+	if minSize > 0 || maxSize > 0 {
+		if maxSize == 0 {
+			maxSize = 5 * 1024 * 1024 * 1024
+		}
+		conditions = append(conditions, []interface{}{"content-length-range", minSize, maxSize})
+	}
+	policy, err := json.Marshal(map[string]interface{}{
+		"expiration": now.Add(expires).Format("2006-01-02T15:04:05.000Z"),
+		"conditions": conditions,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create policy, %s", err)
+	}
+	// assina a política
+	fields["policy"] = base64.StdEncoding.EncodeToString(policy)
+	fields["x-amz-signature"] = hex.EncodeToString(hmacSHA256(signingKey(myConfig.SecretKey, date, s3region, "s3"), fields["policy"]))
+	// exibe o formulário
+	data, err := json.MarshalIndent(presignedPost{URL: postURL(), Fields: fields}, "", "  ")
+	if err != nil {
+		return err
+	}
+	log.Printf("presigned post policy for files starting with {%s} expires at %s", prefix, now.Add(expires).Format(time.RFC3339))
+	fmt.Println(string(data))
+	return nil
+}
+
+// define a url do bucket para o envio do formulário
+func postURL() string {
+	if myConfig.EndPoint != "" {
+		return strings.TrimSuffix(myConfig.EndPoint, "/") + "/" + myConfig.Bucket
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com", myConfig.Bucket, s3region)
+}
+
+// gera a chave de assinatura derivada da chave secreta
+func signingKey(secret string, date string, region string, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// calcula o hmac sha256 do conteúdo
+func hmacSHA256(key []byte, content string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(content))
+	return h.Sum(nil)
+}
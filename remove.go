@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// processa o comando de remoção de arquivos do bucket
+func processRemove(args []string) {
+	// identifica os flags informados
+	cmdRemove := flag.NewFlagSet("rm", flag.ExitOnError)
+	// define os parametros para sobrescrever o padrão configurado
+	pBucketFlags := addBucketFlags(cmdRemove)
+	// define os parametros para utilização específicos para este método
+	pFilter := cmdRemove.String("f", "", "filter to select files")
+	pDryRun := cmdRemove.Bool("dryrun", false, "only show the files that would be removed")
+	pErrorNoFiles := cmdRemove.Bool("enf", false, "terminate with exit code 1 if no files found")
+	// processa os parametros
+	err := cmdRemove.Parse(args)
+	if err != nil || len(args) == 0 {
+		cmdRemove.Usage()
+		os.Exit(1)
+	}
+	// aplica os parametros de acesso ao bucket
+	pBucketFlags.apply()
+	// valida o filtro
+	if *pFilter == "" {
+		log.Fatalf("file name filter not provided")
+	}
+	// valida se há parametros suficientes
+	if myConfig.Bucket == "" {
+		cmdRemove.Usage()
+		os.Exit(1)
+	}
+	// inicializa o serviço da aws
+	err = connect(*pBucketFlags.Role)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// executa as remoções
+	err = removeFiles(*pFilter, *pBucketFlags.Prefix, *pDryRun, *pErrorNoFiles)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Remove do bucket todos os arquivos que atendem ao filtro especificado
+func removeFiles(filter string, prefix string, dryRun bool, errornofiles bool) error {
+	// loga o endpoint e o bucket que será usado
+	logEndpoint()
+	// ajusta os campos traduzindo as variaveis se utilizadas
+	prefix = parseName("", prefix)
+	filter = parseName("", filter)
+	// seleciona os objetos do bucket que atendem ao filtro
+	matches, err := selectObjects(filter, prefix)
+	if err != nil {
+		return err
+	}
+	// a chave sem curingas só é selecionada se existir no bucket
+	if !hasWildcard(filter) {
+		exists, err := keyExists(prefix + filter)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			matches = nil
+		}
+	}
+	// verifica se foi selecionado algum arquivo
+	if len(matches) == 0 {
+		log.Printf("no files found in bucket {%s} with filter {%s}", myConfig.Bucket, filter)
+		if errornofiles {
+			os.Exit(1)
+		}
+		return nil
+	}
+	// lista os arquivos
+	keys := make([]string, len(matches))
+	for k, v := range matches {
+		keys[k] = *v.Key
+		log.Printf("[%d] selected to remove: %s", k, keys[k])
+	}
+	if dryRun {
+		return nil
+	}
+	return removeKeys(keys)
+}
+
+// remove as chaves do bucket exibindo o resultado de cada uma
+func removeKeys(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	failures := deleteObjects(keys)
+	for k, v := range keys {
+		if err, ok := failures[v]; ok {
+			log.Printf("[%d] unable to remove file {%s}, %s", k, v, err)
+		} else {
+			log.Printf("[%d] file {%s} removed successfully", k, v)
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to remove %d of %d files", len(failures), len(keys))
+	}
+	return nil
+}
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Define o checkpoint de um envio multipart para permitir retomar o
+// envio a partir das partes que ainda não foram enviadas
+type uploadCheckpoint struct {
+	Bucket   string           `json:"bucket"`
+	Key      string           `json:"key"`
+	File     string           `json:"file"`
+	Size     int64            `json:"size"`
+	ModTime  time.Time        `json:"mod_time"`
+	PartSize int64            `json:"part_size"`
+	UploadId string           `json:"upload_id"`
+	Parts    []checkpointPart `json:"parts"`
+	// caminho do arquivo de checkpoint
+	path string
+	// controla a gravação concorrente das partes
+	mutex sync.Mutex
+}
+
+// Define o checkpoint de uma recepção, o arquivo parcial é mantido
+// enquanto o ETag do objeto não for alterado
+type downloadCheckpoint struct {
+	Bucket  string `json:"bucket"`
+	Key     string `json:"key"`
+	Partial string `json:"partial"`
+	ETag    string `json:"etag"`
+	Size    int64  `json:"size"`
+}
+
+// Define uma parte enviada
+type checkpointPart struct {
+	PartNumber int32  `json:"part_number"`
+	ETag       string `json:"etag"`
+}
+
+// retorna a pasta onde são gravados os checkpoints
+func checkpointFolder() string {
+	if myConfig.CheckpointFolder != "" {
+		return myConfig.CheckpointFolder
+	}
+	if configDir != "" {
+		return filepath.Join(configDir, ".s3checkpoint")
+	}
+	return filepath.Join(os.TempDir(), "s3checkpoint")
+}
+
+// retorna o caminho do checkpoint de envio do arquivo para o bucket
+func uploadCheckpointPath(file string) string {
+	return checkpointPath("put", myConfig.Bucket, file)
+}
+
+// retorna o caminho do checkpoint de recepção do objeto para o arquivo
+func downloadCheckpointPath(key string, file string) string {
+	return checkpointPath("get", myConfig.Bucket+"\n"+key, file)
+}
+
+// define o nome do arquivo de checkpoint a partir da operação, do
+// objeto e do caminho absoluto do arquivo local
+func checkpointPath(operation string, object string, file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		abs = file
+	}
+	sum := sha1.Sum([]byte(object + "\n" + abs))
+	return filepath.Join(checkpointFolder(), operation+"-"+hex.EncodeToString(sum[:])+".json")
+}
+
+// carrega o checkpoint de envio do arquivo, retorna nulo se não existir
+func loadUploadCheckpoint(file string) (*uploadCheckpoint, error) {
+	cp := &uploadCheckpoint{path: uploadCheckpointPath(file)}
+	found, err := readCheckpoint(cp.path, cp)
+	if err != nil || !found {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// grava o checkpoint em disco
+func (p *uploadCheckpoint) save() error {
+	return writeCheckpoint(p.path, p)
+}
+
+// registra a parte enviada e grava o checkpoint
+func (p *uploadCheckpoint) addPart(number int32, etag string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.Parts = append(p.Parts, checkpointPart{PartNumber: number, ETag: etag})
+	return p.save()
+}
+
+// remove o checkpoint do disco
+func (p *uploadCheckpoint) remove() error {
+	return removeCheckpoint(p.path)
+}
+
+// lê o checkpoint do arquivo, retorna falso se o checkpoint não existir
+func readCheckpoint(path string, v interface{}) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to read checkpoint {%s}, %s", path, err)
+	}
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return false, fmt.Errorf("unable to decode checkpoint {%s}, %s", path, err)
+	}
+	return true, nil
+}
+
+// grava o checkpoint no arquivo, a gravação é feita em um arquivo
+// temporário para não corromper o checkpoint em caso de interrupção
+func writeCheckpoint(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0774)
+	if err != nil {
+		return fmt.Errorf("unable to create checkpoint folder, %s", err)
+	}
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, data, 0664)
+	if err != nil {
+		return fmt.Errorf("unable to write checkpoint {%s}, %s", path, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// remove o arquivo de checkpoint
+func removeCheckpoint(path string) error {
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Realiza o envio multipart do arquivo mantendo um checkpoint local, caso
+// exista um checkpoint válido do arquivo apenas as partes que ainda não
+// foram recebidas pelo bucket são enviadas. Retorna o ETag esperado
+// para o objeto e na verificação o md5 de cada parte enviada é comparado
+// com o ETag da parte
+func sendResumable(f *os.File, stat os.FileInfo, key string, metaData map[string]string, tagging string, partSize int64, verify bool) (string, *manager.UploadOutput, error) {
+	// carrega o checkpoint do arquivo
+	cp, err := loadUploadCheckpoint(f.Name())
+	if err != nil {
+		return "", nil, err
+	}
+	// identifica as partes já recebidas pelo bucket
+	done := make(map[int32]string)
+	if cp != nil {
+		discard := ""
+		switch {
+		case cp.Size != stat.Size() || !cp.ModTime.Equal(stat.ModTime()):
+			discard = "file changed"
+		case cp.Key != key:
+			discard = fmt.Sprintf("checkpoint created for key {%s}", cp.Key)
+		case cp.PartSize < clampPartSize(cp.Size, 0):
+			discard = fmt.Sprintf("invalid part size %d in checkpoint", cp.PartSize)
+		}
+		if discard != "" {
+			// o envio anterior é descartado e um novo envio é iniciado
+			log.Printf("discarding checkpoint of file {%s}, %s, aborting upload {%s}", f.Name(), discard, cp.UploadId)
+			err = abortUpload(cp)
+			if err != nil {
+				log.Printf("unable to abort upload {%s} of file {%s}, %s", cp.UploadId, f.Name(), err)
+			}
+			cp = nil
+		} else {
+			done, err = listUploadedParts(cp)
+			if err != nil {
+				var nsu *types.NoSuchUpload
+				if !errors.As(err, &nsu) {
+					return "", nil, err
+				}
+				// o envio não existe mais no bucket
+				log.Printf("upload {%s} of checkpoint no longer exists, starting a new upload", cp.UploadId)
+				err = cp.remove()
+				if err != nil {
+					log.Printf("unable to remove checkpoint {%s}, %s", cp.path, err)
+				}
+				cp = nil
+			}
+		}
+	}
+	// inicia um novo envio multipart se necessário
+	if cp == nil {
+		input := &s3.CreateMultipartUploadInput{
+			Bucket:   aws.String(myConfig.Bucket),
+			Key:      aws.String(key),
+			Metadata: metaData,
+		}
+		if tagging != "" {
+			input.Tagging = aws.String(tagging)
+		}
+		output, err := s3client.CreateMultipartUpload(context.TODO(), input)
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to create multipart upload, %s", err)
+		}
+		cp = &uploadCheckpoint{
+			Bucket:   myConfig.Bucket,
+			Key:      key,
+			File:     f.Name(),
+			Size:     stat.Size(),
+			ModTime:  stat.ModTime(),
+			PartSize: clampPartSize(stat.Size(), partSize),
+			UploadId: aws.ToString(output.UploadId),
+			path:     uploadCheckpointPath(f.Name()),
+		}
+		done = make(map[int32]string)
+	}
+	// mantém no checkpoint apenas as partes confirmadas pelo bucket
+	cp.Parts = nil
+	for number, etag := range done {
+		cp.Parts = append(cp.Parts, checkpointPart{PartNumber: number, ETag: etag})
+	}
+	err = cp.save()
+	if err != nil {
+		return "", nil, err
+	}
+	// envia as partes que estão faltando
+	total := int((cp.Size + cp.PartSize - 1) / cp.PartSize)
+	if len(done) > 0 {
+		log.Printf("resuming upload {%s} of file {%s}, %d of %d parts already uploaded", cp.UploadId, f.Name(), len(done), total)
+	}
+	var pending []int32
+	for i := 1; i <= total; i++ {
+		if _, ok := done[int32(i)]; !ok {
+			pending = append(pending, int32(i))
+		}
+	}
+	errs := make([]error, len(pending))
+	runWorkers(len(pending), manager.DefaultUploadConcurrency, func(k int) {
+		number := pending[k]
+		offset := int64(number-1) * cp.PartSize
+		length := cp.PartSize
+		if offset+length > cp.Size {
+			length = cp.Size - offset
+		}
+		body := newPartReader(f, offset, length)
+		output, err := s3client.UploadPart(context.TODO(), &s3.UploadPartInput{
+			Bucket:        aws.String(cp.Bucket),
+			Key:           aws.String(cp.Key),
+			UploadId:      aws.String(cp.UploadId),
+			PartNumber:    number,
+			ContentLength: length,
+			Body:          body,
+		})
+		if err != nil {
+			errs[k] = fmt.Errorf("unable to upload part %d, %s", number, err)
+			return
+		}
+		// compara o md5 do conteúdo lido com o ETag da parte
+		remote := strings.Trim(aws.ToString(output.ETag), `"`)
+		if verify && comparableETag(output.ServerSideEncryption, output.SSECustomerKeyMD5) && body.MD5() != remote {
+			errs[k] = fmt.Errorf("checksum mismatch, part %d etag {%s} differs from file md5 {%s}", number, remote, body.MD5())
+			return
+		}
+		errs[k] = cp.addPart(number, aws.ToString(output.ETag))
+	})
+	for _, err := range errs {
+		if err != nil {
+			return "", nil, fmt.Errorf("%s, checkpoint kept to resume upload {%s}", err, cp.UploadId)
+		}
+	}
+	// conclui o envio com as partes ordenadas
+	parts := make([]types.CompletedPart, len(cp.Parts))
+	for k, v := range cp.Parts {
+		parts[k] = types.CompletedPart{PartNumber: v.PartNumber, ETag: aws.String(v.ETag)}
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	etags := make([]string, len(parts))
+	for k, v := range parts {
+		etags[k] = aws.ToString(v.ETag)
+	}
+	etag, err := multipartETag(etags)
+	if err != nil {
+		return "", nil, err
+	}
+	output, err := s3client.CompleteMultipartUpload(context.TODO(), &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(cp.Bucket),
+		Key:             aws.String(cp.Key),
+		UploadId:        aws.String(cp.UploadId),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to complete multipart upload, %s, checkpoint kept to resume upload {%s}", err, cp.UploadId)
+	}
+	// remove o checkpoint pois o envio foi concluído
+	err = cp.remove()
+	if err != nil {
+		log.Printf("unable to remove checkpoint {%s}, %s", cp.path, err)
+	}
+	return etag, &manager.UploadOutput{
+		Location:  aws.ToString(output.Location),
+		VersionID: output.VersionId,
+		UploadID:  cp.UploadId,
+	}, nil
+}
+
+// lista as partes do envio multipart que já foram recebidas pelo bucket,
+// as partes com tamanho diferente do esperado são descartadas
+func listUploadedParts(cp *uploadCheckpoint) (map[int32]string, error) {
+	done := make(map[int32]string)
+	paginator := s3.NewListPartsPaginator(s3client, &s3.ListPartsInput{
+		Bucket:   aws.String(cp.Bucket),
+		Key:      aws.String(cp.Key),
+		UploadId: aws.String(cp.UploadId),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("unable to list parts of upload {%s}, %w", cp.UploadId, err)
+		}
+		for _, v := range output.Parts {
+			length := cp.PartSize
+			if offset := int64(v.PartNumber-1) * cp.PartSize; offset+length > cp.Size {
+				length = cp.Size - offset
+			}
+			if v.Size == length {
+				done[v.PartNumber] = aws.ToString(v.ETag)
+			}
+		}
+	}
+	return done, nil
+}
+
+// cancela o envio multipart do checkpoint e remove o checkpoint
+func abortUpload(cp *uploadCheckpoint) error {
+	_, err := s3client.AbortMultipartUpload(context.TODO(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(cp.Bucket),
+		Key:      aws.String(cp.Key),
+		UploadId: aws.String(cp.UploadId),
+	})
+	if err != nil {
+		var nsu *types.NoSuchUpload
+		if !errors.As(err, &nsu) {
+			return fmt.Errorf("unable to abort upload {%s}, %s", cp.UploadId, err)
+		}
+	}
+	return cp.remove()
+}
+
+// calcula o checksum dos bytes já recebidos do arquivo parcial
+func hashPartial(partial string, offset int64, h *contentHash) error {
+	f, err := os.Open(partial)
+	if err != nil {
+		return fmt.Errorf("unable to open file {%s}, %s", partial, err)
+	}
+	defer f.Close()
+	_, err = io.Copy(h, io.NewSectionReader(f, 0, offset))
+	if err != nil {
+		return fmt.Errorf("unable to read file {%s}, %s", partial, err)
+	}
+	return nil
+}
+
+// Cancela os envios multipart pendentes dos arquivos que possuem checkpoint
+func abortUploads(items []uploadItem) error {
+	var failed int
+	for k, v := range items {
+		cp, err := loadUploadCheckpoint(v.Path)
+		if err == nil && cp == nil {
+			log.Printf("[%d] no checkpoint found for file {%s}", k, v.Path)
+			continue
+		}
+		if err == nil {
+			err = abortUpload(cp)
+		}
+		if err != nil {
+			failed++
+			log.Printf("[%d] unable to abort upload of file {%s}, %s", k, v.Path, err)
+			continue
+		}
+		log.Printf("[%d] upload {%s} of file {%s} aborted successfully", k, cp.UploadId, v.Path)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to abort %d of %d uploads", failed, len(items))
+	}
+	return nil
+}
+
+// Realiza a recepção do objeto mantendo um arquivo parcial e o ETag do
+// objeto, caso a recepção seja interrompida a próxima execução recebe
+// apenas os bytes que estão faltando usando requisições com range
+func receiveResumable(key string, filePath string, verify string) (n int64, err error) {
+	// identifica a versão atual do objeto
+	head, err := s3client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(myConfig.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to read properties of object, %s", err)
+	}
+	etag := aws.ToString(head.ETag)
+	size := head.ContentLength
+	// carrega o checkpoint e identifica quanto do arquivo já foi recebido
+	path := downloadCheckpointPath(key, filePath)
+	partial := filePath + ".s3part"
+	cp := &downloadCheckpoint{}
+	found, err := readCheckpoint(path, cp)
+	if err != nil {
+		return 0, err
+	}
+	var offset int64
+	if found && cp.ETag == etag && cp.Size == size && cp.Partial == partial {
+		if stat, err := os.Stat(partial); err == nil && stat.Size() <= size {
+			offset = stat.Size()
+		}
+	} else if found {
+		log.Printf("object {%s} changed since checkpoint, discarding partial file {%s}", key, cp.Partial)
+		os.Remove(cp.Partial)
+	}
+	// grava o checkpoint com a versão atual do objeto
+	cp = &downloadCheckpoint{
+		Bucket:  myConfig.Bucket,
+		Key:     key,
+		Partial: partial,
+		ETag:    etag,
+		Size:    size,
+	}
+	err = writeCheckpoint(path, cp)
+	if err != nil {
+		return 0, err
+	}
+	// abre o arquivo parcial a partir do ponto já recebido
+	f, err := os.OpenFile(partial, os.O_WRONLY|os.O_CREATE, 0774)
+	if err != nil {
+		return 0, fmt.Errorf("unable to create file, %s", err)
+	}
+	defer f.Close()
+	err = f.Truncate(offset)
+	if err == nil {
+		_, err = f.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to resume file, %s", err)
+	}
+	// na verificação calcula o checksum do conteúdo já recebido e dos
+	// bytes recebidos a seguir enquanto são gravados
+	var w io.Writer = f
+	var h *contentHash
+	if verify != "" {
+		h, err = newContentHash(verify, etagPartSize(strings.Trim(etag, `"`), size))
+		if err != nil {
+			return 0, err
+		}
+		if offset > 0 {
+			err = hashPartial(partial, offset, h)
+			if err != nil {
+				return 0, err
+			}
+		}
+		w = io.MultiWriter(f, h)
+	}
+	// recebe apenas os bytes que estão faltando, a recepção só é aceita
+	// se o objeto ainda possuir o mesmo ETag
+	if offset < size {
+		if offset > 0 {
+			log.Printf("resuming download of object {%s} from byte %d of %d", key, offset, size)
+		}
+		output, err := s3client.GetObject(context.TODO(), &s3.GetObjectInput{
+			Bucket:  aws.String(myConfig.Bucket),
+			Key:     aws.String(key),
+			Range:   aws.String(fmt.Sprintf("bytes=%d-", offset)),
+			IfMatch: aws.String(etag),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("unable to download file, %s, partial file kept to resume download", err)
+		}
+		defer output.Body.Close()
+		_, err = io.Copy(w, output.Body)
+		if err != nil {
+			return 0, fmt.Errorf("unable to download file, %s, partial file kept to resume download", err)
+		}
+	}
+	// garante que o arquivo foi recebido por completo
+	err = f.Sync()
+	if err != nil {
+		return 0, fmt.Errorf("unable to write file, %s", err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("unable to read properties of file, %s", err)
+	}
+	if stat.Size() != size {
+		return 0, fmt.Errorf("file size %d differs from object size %d, partial file kept to resume download", stat.Size(), size)
+	}
+	f.Close()
+	// verifica a integridade do arquivo, em caso de divergência o arquivo
+	// parcial é descartado para que a próxima recepção comece do início
+	if verify != "" {
+		err = verifyDownload(filePath, stat.Size(), head, h)
+		if err != nil {
+			os.Remove(partial)
+			removeCheckpoint(path)
+			return 0, err
+		}
+	}
+	// move o arquivo parcial para o nome definitivo
+	err = os.Rename(partial, filePath)
+	if err != nil {
+		return 0, fmt.Errorf("unable to rename partial file, %s", err)
+	}
+	err = removeCheckpoint(path)
+	if err != nil {
+		log.Printf("unable to remove checkpoint {%s}, %s", path, err)
+	}
+	return size, nil
+}
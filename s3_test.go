@@ -1,9 +1,22 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 func TestRename(t *testing.T) {
@@ -35,14 +48,41 @@ func TestRename(t *testing.T) {
 	}
 }
 
+func TestParseNameParallel(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if n := parseName("teste.txt", "#FN_#R4#FE"); !strings.HasPrefix(n, "teste_") {
+					t.Logf("[parseName] random name {%s} without file name", n)
+					t.Fail()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func TestWildcardToRegexp(t *testing.T) {
 	in := map[string]string{
-		"*":        ".*",
-		"*teste*":  ".*teste.*",
-		"teste*":   "teste.*",
-		"*teste":   ".*teste",
-		"te*ste":   "te.*ste",
-		"***teste": ".*teste",
+		"*":          "[^/]*",
+		"*teste*":    "[^/]*teste[^/]*",
+		"teste*":     "teste[^/]*",
+		"*teste":     "[^/]*teste",
+		"te*ste":     "te[^/]*ste",
+		"***teste":   ".*teste",
+		"*.TXT":      `[^/]*\.TXT`,
+		"**/*.csv":   `(.*/)?[^/]*\.csv`,
+		"a/**":       "a/.*",
+		"file?.txt":  `file[^/]\.txt`,
+		"[a-z]*":     "[a-z][^/]*",
+		"[^0-9]x":    "[^/0-9]x",
+		`a\*b`:       `a\*b`,
+		"(x)+":       `\(x\)\+`,
+		`[\]a]`:      `[\]a]`,
+		"INV_[0-9]*": "INV_[0-9][^/]*",
 	}
 	for k, v := range in {
 		n := wildCardToRegexp(k)
@@ -52,3 +92,783 @@ func TestWildcardToRegexp(t *testing.T) {
 		}
 	}
 }
+
+func TestKeyPattern(t *testing.T) {
+	in := map[string]map[string]bool{
+		"*.TXT": {
+			"in/a.TXT":     true,
+			"in/a.TXT.bak": false,
+			"in/xTXT":      false,
+			"in/sub/a.TXT": false,
+		},
+		"**/*.TXT": {
+			"in/a.TXT":       true,
+			"in/sub/a.TXT":   true,
+			"in/sub/x/a.TXT": true,
+			"in/sub/a.TXTx":  false,
+		},
+		"**": {
+			"in/a":     true,
+			"in/sub/a": true,
+		},
+		"(1).txt": {
+			"in/(1).txt": true,
+			"in/1.txt":   false,
+		},
+	}
+	for filter, keys := range in {
+		pattern, err := keyPattern("in/", filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range keys {
+			if pattern.MatchString(k) != v {
+				t.Logf("[keyPattern] %s with filter {%s} => %v != %v", k, filter, !v, v)
+				t.Fail()
+			}
+		}
+	}
+	for _, v := range []string{"[a-", "[a-]", "[^]", `a\`, "[]a]"} {
+		if _, err := keyPattern("in/", v); err == nil {
+			t.Logf("[keyPattern] invalid filter {%s} accepted", v)
+			t.Fail()
+		}
+	}
+	// sem "**" o resultado deve ser o mesmo do filepath.Glob
+	filters := []string{"*", "*.txt", "a?c.txt", "[a-c]*", "[^a]*", `\*.txt`, "sub/*.txt", "*/*"}
+	names := []string{"abc.txt", "a.txt", "b", "*.txt", "sub/a.txt", "sub/b/c.txt", "x.TXT"}
+	for _, filter := range filters {
+		pattern, err := globPattern(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			expected, _ := path.Match(filter, name)
+			if pattern.MatchString(name) != expected {
+				t.Logf("[globPattern] %s with filter {%s} => %v != %v", name, filter, !expected, expected)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestObjectName(t *testing.T) {
+	in := map[string][]string{
+		"out/a.csv":         {"#FN#FE", "a.csv"},
+		"out/2024/06/a.csv": {"#FN#FE", "2024/06/a.csv"},
+		"out/2024/b.csv":    {"X_#FN#FE", "2024/X_b.csv"},
+	}
+	for k, v := range in {
+		n := objectName("out", k, v[0], true)
+		if n != v[1] {
+			t.Logf("[objectName] relative key for {%s} => {%s} != {%s}", k, n, v[1])
+			t.Fail()
+		}
+	}
+	if n := objectName("out", "out/2024/06/a.csv", "#FN#FE", false); n != "a.csv" {
+		t.Logf("[objectName] flat key for {out/2024/06/a.csv} => {%s} != {a.csv}", n)
+		t.Fail()
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	in := map[string][]string{
+		"in/a.csv":         {LayoutFlat, filepath.Join("out", "a.csv")},
+		"in/2024/06/a.csv": {LayoutFlat, filepath.Join("out", "a.csv")},
+		"in/2024/06/b.csv": {LayoutTree, filepath.Join("out", "2024", "06", "b.csv")},
+	}
+	for k, v := range in {
+		n, err := localPath("out", "in/", k, "#FN#FE", v[0])
+		if err != nil || n != v[1] {
+			t.Logf("[localPath] path for {%s} => {%s} != {%s}, %v", k, n, v[1], err)
+			t.Fail()
+		}
+	}
+	if _, err := localPath("out", "in/", "in/../../x.csv", "#FN#FE", LayoutTree); err == nil {
+		t.Logf("[localPath] key outside of folder must fail")
+		t.Fail()
+	}
+	// o renomeio com grupos capturados pode gerar pastas no nome
+	for _, layout := range []string{LayoutFlat, LayoutTree} {
+		if _, err := localPath("out", "in/", "in/x.csv", "../../etc/#FN#FE", layout); err == nil {
+			t.Logf("[localPath] %s name outside of folder must fail", layout)
+			t.Fail()
+		}
+	}
+}
+
+func TestLocalPathParallel(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				n, err := localPath("out", "in/", "in/2024/a.csv", "#FN_#R2#R4#FE", LayoutTree)
+				if err != nil || !strings.HasPrefix(n, filepath.Join("out", "2024", "a_")) {
+					t.Logf("[localPath] random path {%s} outside of {out/2024}, %v", n, err)
+					t.Fail()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPrintEntry(t *testing.T) {
+	modified := time.Date(2024, 6, 1, 10, 30, 0, 0, time.UTC)
+	object := listEntry{Key: "in/a.csv", Size: 1024, LastModified: &modified, StorageClass: "STANDARD", ETag: "781e5e245d69b566979b86e28d23f2c7"}
+	dir := listEntry{Prefix: "in/2024/"}
+	local := modified.Local().Format("2006-01-02 15:04:05")
+	in := map[string][]string{
+		OutputShort: {"in/a.csv\n", "in/2024/\n"},
+		OutputLong: {
+			local + "           1024 STANDARD             781e5e245d69b566979b86e28d23f2c7   in/a.csv\n",
+			"                               DIR                                                         in/2024/\n",
+		},
+		OutputJSON: {
+			`{"key":"in/a.csv","size":1024,"last_modified":"2024-06-01T10:30:00Z","storage_class":"STANDARD","etag":"781e5e245d69b566979b86e28d23f2c7"}` + "\n",
+			`{"prefix":"in/2024/","size":0}` + "\n",
+		},
+	}
+	for k, v := range in {
+		for i, entry := range []listEntry{object, dir} {
+			var b strings.Builder
+			printEntry(&b, entry, k)
+			if b.String() != v[i] {
+				t.Logf("[printEntry] %s output => {%q} != {%q}", k, b.String(), v[i])
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestTargetKey(t *testing.T) {
+	in := map[string][]string{
+		"in/a.csv":         {LayoutFlat, "#FN#FE", "out/a.csv"},
+		"in/2024/06/a.csv": {LayoutFlat, "#FN#FE", "out/a.csv"},
+		"in/2024/06/b.csv": {LayoutTree, "#FN#FE", "out/2024/06/b.csv"},
+		"in/2024/c.csv":    {LayoutTree, "#FN.bak", "out/2024/c.bak"},
+		"in/d.csv":         {LayoutTree, "#FN#FE", "out/d.csv"},
+	}
+	for k, v := range in {
+		n := targetKey("in/", k, "out/", v[1], v[0])
+		if n != v[2] {
+			t.Logf("[targetKey] %s target of {%s} with {%s} => {%s} != {%s}", v[0], k, v[1], n, v[2])
+			t.Fail()
+		}
+	}
+}
+
+func TestCopyTargets(t *testing.T) {
+	saved := myConfig
+	t.Cleanup(func() { myConfig = saved })
+	myConfig = &Config{Bucket: "bucket"}
+	matches := []types.Object{{Key: aws.String("in/a/x.txt")}, {Key: aws.String("in/b/x.txt")}}
+	// o mesmo destino só é aceito na cópia
+	in := map[string][]bool{
+		LayoutFlat: {true, false},
+		LayoutTree: {true, true},
+	}
+	for k, v := range in {
+		for i, move := range []bool{false, true} {
+			targets, err := copyTargets(matches, "in/", "bucket", "out/", "#FN#FE", k, false, move)
+			if (err == nil) != v[i] {
+				t.Logf("[copyTargets] %s targets with move %v => %v, %v", k, move, targets, err)
+				t.Fail()
+			}
+		}
+	}
+	// o destino igual à origem só é aceito na cópia que substitui os metadados
+	in2 := map[string][]bool{
+		"copy":         {false, false},
+		"copy replace": {true, false},
+		"move replace": {true, true},
+	}
+	for k, v := range in2 {
+		_, err := copyTargets(matches[:1], "in/a/", "bucket", "in/a/", "#FN#FE", LayoutFlat, v[0], v[1])
+		if (err == nil) != (k == "copy replace") {
+			t.Logf("[copyTargets] target equal to source with %s => %v", k, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestCopyPartSize(t *testing.T) {
+	saved := myConfig
+	t.Cleanup(func() { myConfig = saved })
+	myConfig = &Config{}
+	in := map[int64]int64{
+		6 << 30:   64 << 20,
+		50 << 30:  100 << 20,
+		1 << 40:   250 << 20,
+		5 << 40:   549755814,
+		10 << 40:  1099511628,
+		5<<30 + 1: 64 << 20,
+	}
+	for k, v := range in {
+		n := copyPartSize(k)
+		if n != v || (k+n-1)/n > 10000 {
+			t.Logf("[copyPartSize] part size of {%d} => {%d} != {%d}", k, n, v)
+			t.Fail()
+		}
+	}
+}
+
+func TestStreamPartSize(t *testing.T) {
+	saved := myConfig
+	t.Cleanup(func() { myConfig = saved })
+	in := map[int]int64{
+		0:        64 << 20,
+		1 << 20:  64 << 20,
+		5 << 20:  5 << 20,
+		16 << 20: 16 << 20,
+	}
+	for k, v := range in {
+		myConfig = &Config{PartSize: k}
+		n := streamPartSize()
+		if n != v {
+			t.Logf("[streamPartSize] part size with configured {%d} => {%d} != {%d}", k, n, v)
+			t.Fail()
+		}
+	}
+}
+
+func TestFileETag(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "etag.txt")
+	if err := os.WriteFile(file, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	in := map[int64]string{
+		10: "781e5e245d69b566979b86e28d23f2c7",
+		20: "781e5e245d69b566979b86e28d23f2c7",
+		4:  "61e3716e3a7767581863b67c4e785584-3",
+	}
+	for k, v := range in {
+		n, err := fileETag(file, k)
+		if err != nil || n != v {
+			t.Logf("[fileETag] etag with part size {%d} => {%s} != {%s}, %v", k, n, v, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestETagPartSize(t *testing.T) {
+	saved := myConfig
+	t.Cleanup(func() { myConfig = saved })
+	myConfig = &Config{}
+	mb := int64(1024 * 1024)
+	in := map[string][]int64{
+		"abc":    {100, 100},
+		"abc-2":  {100 * mb, 64 * mb},
+		"abc-13": {100 * mb, 8 * mb},
+	}
+	for k, v := range in {
+		n := etagPartSize(k, v[0])
+		if n != v[1] {
+			t.Logf("[etagPartSize] part size for {%s} with size {%d} => {%d} != {%d}", k, v[0], n, v[1])
+			t.Fail()
+		}
+	}
+}
+
+func TestResumablePartSize(t *testing.T) {
+	in := map[int64][]int64{
+		0:                 {5 << 20, 5 << 20},
+		100 << 20:         {5 << 20, 5 << 20},
+		10000 * (5 << 20): {5 << 20, 5<<20 + 1},
+		1 << 40:           {64 << 20, 109951163},
+		5 << 40:           {250 << 20, 549755814},
+	}
+	for k, v := range in {
+		n := clampPartSize(k, v[0])
+		if n != v[1] {
+			t.Logf("[clampPartSize] part size of {%d} with {%d} => {%d} != {%d}", k, v[0], n, v[1])
+			t.Fail()
+		}
+		if (k+n-1)/n > 10000 {
+			t.Logf("[clampPartSize] part size {%d} of {%d} exceeds 10000 parts", n, k)
+			t.Fail()
+		}
+	}
+}
+
+func TestFileChecksum(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "checksum.txt")
+	if err := os.WriteFile(file, []byte("123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	in := map[string]string{
+		ChecksumMD5:    "25f9e794323b453885f5181f1b624d0b",
+		ChecksumSHA256: "15e2b0d3c33891ebb0f1ef609ec419420c20e320ce94c65fbc8c3312448eb225",
+		ChecksumCRC32C: "e3069283",
+	}
+	for k, v := range in {
+		sum, etag, err := fileChecksum(file, k, 4)
+		if err != nil || sum != v {
+			t.Logf("[fileChecksum] %s checksum => {%s} != {%s}, %v", k, sum, v, err)
+			t.Fail()
+		}
+		if !strings.HasSuffix(etag, "-3") {
+			t.Logf("[fileChecksum] etag with 3 parts => {%s}", etag)
+			t.Fail()
+		}
+	}
+}
+
+func TestHashWriterAt(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "hash.txt")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h, err := newContentHash(ChecksumMD5, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &hashWriterAt{w: f, hash: h}
+	// a segunda parte é gravada novamente como em uma nova tentativa
+	in := []struct {
+		off  int64
+		data string
+	}{{0, "0123"}, {4, "45"}, {4, "4567"}, {8, "89"}}
+	for _, v := range in {
+		if _, err := w.WriteAt([]byte(v.data), v.off); err != nil {
+			t.Logf("[hashWriterAt] write {%s} at %d, %v", v.data, v.off, err)
+			t.Fail()
+		}
+	}
+	if h.Sum() != "781e5e245d69b566979b86e28d23f2c7" || h.ETag() != "61e3716e3a7767581863b67c4e785584-3" {
+		t.Logf("[hashWriterAt] checksum {%s} etag {%s}", h.Sum(), h.ETag())
+		t.Fail()
+	}
+	if _, err := w.WriteAt([]byte("x"), 20); err == nil {
+		t.Logf("[hashWriterAt] write after the end of content must fail")
+		t.Fail()
+	}
+}
+
+func TestPartReader(t *testing.T) {
+	r := newPartReader(strings.NewReader("0123456789"), 4, 4)
+	// a parte é lida para a assinatura e novamente para o envio
+	for i := 0; i < 2; i++ {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		if b, err := io.ReadAll(r); err != nil || string(b) != "4567" {
+			t.Logf("[partReader] part => {%s} != {4567}, %v", b, err)
+			t.Fail()
+		}
+	}
+	if n := r.MD5(); n != "6562c5c1f33db6e05a082a88cddab5ea" {
+		t.Logf("[partReader] md5 => {%s} != {6562c5c1f33db6e05a082a88cddab5ea}", n)
+		t.Fail()
+	}
+	r = newPartReader(strings.NewReader("0123456789"), 4, 4)
+	r.Seek(2, io.SeekStart)
+	io.ReadAll(r)
+	if n := r.MD5(); n != "" {
+		t.Logf("[partReader] md5 of partial read => {%s} != {}", n)
+		t.Fail()
+	}
+}
+
+func TestMultipartETag(t *testing.T) {
+	in := map[string][]string{
+		"61e3716e3a7767581863b67c4e785584-3": {`"eb62f6b9306db575c2d596b1279627a4"`, "6562c5c1f33db6e05a082a88cddab5ea", "7647966b7343c29048673252e490f736"},
+	}
+	for k, v := range in {
+		n, err := multipartETag(v)
+		if err != nil || n != k {
+			t.Logf("[multipartETag] etag of parts %v => {%s} != {%s}, %v", v, n, k, err)
+			t.Fail()
+		}
+	}
+	if _, err := multipartETag([]string{"xyz"}); err == nil {
+		t.Logf("[multipartETag] invalid part etag must fail")
+		t.Fail()
+	}
+}
+
+func TestCompareDownload(t *testing.T) {
+	sum := "15e2b0d3c33891ebb0f1ef609ec419420c20e320ce94c65fbc8c3312448eb225"
+	in := map[string]bool{
+		// ETag de parte única divergente
+		"781e5e245d69b566979b86e28d23f2c7": false,
+		// ETag multipart com tamanho de parte desconhecido
+		"61e3716e3a7767581863b67c4e785584-2": true,
+		"61e3716e3a7767581863b67c4e785584-3": true,
+	}
+	for k, v := range in {
+		head := &s3.HeadObjectOutput{
+			ETag:     aws.String(`"` + k + `"`),
+			Metadata: map[string]string{checksumMetadata + ChecksumSHA256: sum},
+		}
+		err := compareDownload("teste.txt", sum, "61e3716e3a7767581863b67c4e785584-3", head, ChecksumSHA256)
+		if (err == nil) != v {
+			t.Logf("[compareDownload] object etag {%s} => %v", k, err)
+			t.Fail()
+		}
+	}
+	head := &s3.HeadObjectOutput{
+		ETag:     aws.String(`"61e3716e3a7767581863b67c4e785584-2"`),
+		Metadata: map[string]string{checksumMetadata + ChecksumSHA256: "0"},
+	}
+	if err := compareDownload("teste.txt", sum, "61e3716e3a7767581863b67c4e785584-3", head, ChecksumSHA256); err == nil {
+		t.Logf("[compareDownload] stored checksum mismatch must fail")
+		t.Fail()
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	// exemplo da documentação da assinatura versão 4 da aws
+	key := fmt.Sprintf("%x", signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam"))
+	expected := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if key != expected {
+		t.Logf("[signingKey] => {%s} != {%s}", key, expected)
+		t.Fail()
+	}
+}
+
+func TestPrintStat(t *testing.T) {
+	modified := time.Date(2024, 6, 1, 10, 30, 0, 0, time.UTC)
+	entry := &statEntry{
+		Key:          "in/a.csv",
+		Size:         1024,
+		LastModified: &modified,
+		ETag:         "781e5e245d69b566979b86e28d23f2c7",
+		ContentType:  "text/csv",
+		Encryption:   "AES256",
+		Metadata:     map[string]string{"origin": "erp", "checksum-md5": "781e5e245d69b566979b86e28d23f2c7"},
+	}
+	in := map[string]string{
+		"short": "key:            in/a.csv\n" +
+			"size:           1024\n" +
+			"last modified:  " + modified.Local().Format("2006-01-02 15:04:05") + "\n" +
+			"etag:           781e5e245d69b566979b86e28d23f2c7\n" +
+			"content type:   text/csv\n" +
+			"storage class:  STANDARD\n" +
+			"encryption:     AES256\n" +
+			"metadata:      \n" +
+			"  checksum-md5=781e5e245d69b566979b86e28d23f2c7\n" +
+			"  origin=erp\n" +
+			"\n",
+		"json": `{"key":"in/a.csv","size":1024,"last_modified":"2024-06-01T10:30:00Z","etag":"781e5e245d69b566979b86e28d23f2c7",` +
+			`"content_type":"text/csv","encryption":"AES256","metadata":{"checksum-md5":"781e5e245d69b566979b86e28d23f2c7","origin":"erp"}}` + "\n",
+	}
+	for k, v := range in {
+		var b strings.Builder
+		if k == "json" {
+			json.NewEncoder(&b).Encode(entry)
+		} else {
+			printStat(&b, entry)
+		}
+		if b.String() != v {
+			t.Logf("[printStat] %s output => {%q} != {%q}", k, b.String(), v)
+			t.Fail()
+		}
+	}
+}
+
+func TestUsagePrefix(t *testing.T) {
+	in := map[string][]string{
+		"in/a.txt":            {"0", "in/"},
+		"in/2024/06/a.txt":    {"0", "in/"},
+		"in/b.txt":            {"1", "in/"},
+		"in/2024/b.txt":       {"1", "in/2024/"},
+		"in/2024/06/01/b.txt": {"1", "in/2024/"},
+		"in/2024/06/01/c.txt": {"2", "in/2024/06/"},
+	}
+	for k, v := range in {
+		var depth int
+		fmt.Sscan(v[0], &depth)
+		name := usagePrefix("in/", k, depth)
+		if name != v[1] {
+			t.Logf("[usagePrefix] %s with depth %d => {%s} != {%s}", k, depth, name, v[1])
+			t.Fail()
+		}
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	in := map[int64]string{
+		0:                  "0B",
+		1023:               "1023B",
+		1536:               "1.5KB",
+		5 * 1024 * 1024:    "5.0MB",
+		3 << 40:            "3.0TB",
+		1024 * 1024 * 1024: "1.0GB",
+	}
+	for k, v := range in {
+		s := humanSize(k)
+		if s != v {
+			t.Logf("[humanSize] %d => {%s} != {%s}", k, s, v)
+			t.Fail()
+		}
+	}
+}
+
+func TestObjectTagging(t *testing.T) {
+	tags, err := parseTags("partner = acme; file=#FN; type=#FE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := map[string]string{
+		"out/a.csv":  "file=a&partner=acme&type=.csv",
+		"report":     "file=report&partner=acme&type=",
+		"x y/b&c.gz": "file=b%26c&partner=acme&type=.gz",
+	}
+	for k, v := range in {
+		tagging := objectTagging(tags, k)
+		if tagging != v {
+			t.Logf("[objectTagging] %s => {%s} != {%s}", k, tagging, v)
+			t.Fail()
+		}
+	}
+	if _, err := parseTags("a=1;b"); err == nil {
+		t.Logf("[parseTags] invalid tag accepted")
+		t.Fail()
+	}
+}
+
+func TestReadLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"rules.yaml": "rules:\n  - id: archive\n    prefix: in/\n    expiration_days: 365\n    abort_incomplete_multipart_days: 7\n    transitions:\n      - days: 30\n        storage_class: standard_ia\n",
+		"rules.json": `{"rules":[{"id":"archive","prefix":"in/","expiration_days":365,"abort_incomplete_multipart_days":7,"transitions":[{"days":30,"storage_class":"STANDARD_IA"}]}]}`,
+	}
+	for k, v := range files {
+		file := filepath.Join(dir, k)
+		if err := os.WriteFile(file, []byte(v), 0644); err != nil {
+			t.Fatal(err)
+		}
+		rules, err := readLifecycle(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, err := rules.bucketConfiguration()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rule := config.Rules[0]
+		if len(config.Rules) != 1 || *rule.ID != "archive" || rule.Expiration.Days != 365 || rule.AbortIncompleteMultipartUpload.DaysAfterInitiation != 7 || rule.Transitions[0].Days != 30 || rule.Transitions[0].StorageClass != "STANDARD_IA" {
+			t.Logf("[readLifecycle] %s => %+v", k, rule)
+			t.Fail()
+		}
+	}
+}
+
+func TestLifecycleFilter(t *testing.T) {
+	in := map[string]lifecycleRule{
+		"*types.LifecycleRuleFilterMemberPrefix":                {ID: "prefix", Prefix: "in/"},
+		"*types.LifecycleRuleFilterMemberTag":                   {ID: "tag", Tags: map[string]string{"retention": "90d"}},
+		"*types.LifecycleRuleFilterMemberObjectSizeGreaterThan": {ID: "greater", ObjectSizeGreaterThan: 1024},
+		"*types.LifecycleRuleFilterMemberAnd":                   {ID: "and", Prefix: "in/", Tags: map[string]string{"retention": "90d", "partner": "acme"}, ObjectSizeLessThan: 4096},
+	}
+	for k, v := range in {
+		rule := v
+		rule.ExpirationDays = 30
+		config, err := (&lifecycleConfig{Rules: []lifecycleRule{rule}}).bucketConfiguration()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filter := fmt.Sprintf("%T", config.Rules[0].Filter); filter != k {
+			t.Logf("[lifecycleRule] %s filter => {%s} != {%s}", rule.ID, filter, k)
+			t.Fail()
+		}
+		// a regra lida do bucket deve manter todas as condições
+		result, err := newLifecycleConfig(config.Rules)
+		if err != nil || fmt.Sprint(result.Rules[0]) != fmt.Sprint(rule) {
+			t.Logf("[newLifecycleConfig] %s rule => %+v != %+v, %v", rule.ID, result.Rules[0], rule, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestFileFilterRename(t *testing.T) {
+	cmd := flag.NewFlagSet("test", flag.ContinueOnError)
+	filter, err := parseFilterFlags(cmd, "-fr", `^INV_(?P<date>[0-9]{8})_(ACK|NAK)\.xml$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := map[string]string{
+		"#G{date}/#G2_#FN#FE": "20240601/ACK_INV_20240601_ACK.xml",
+		"#G1#G{2}.xml":        "20240601ACK.xml",
+		"#G0":                 "INV_20240601_ACK.xml",
+	}
+	for k, v := range in {
+		if err := filter.validateRename(k); err != nil {
+			t.Fatal(err)
+		}
+		name := parseName("INV_20240601_ACK.xml", filter.rename(k, "INV_20240601_ACK.xml"))
+		if name != v {
+			t.Logf("[fileFilter] rename with {%s} => {%s} != {%s}", k, name, v)
+			t.Fail()
+		}
+	}
+	for _, v := range []string{"#G3", "#G{name}"} {
+		if err := filter.validateRename(v); err == nil {
+			t.Logf("[fileFilter] rename with unknown group {%s} accepted", v)
+			t.Fail()
+		}
+	}
+	cmd = flag.NewFlagSet("test", flag.ContinueOnError)
+	if _, err := parseFilterFlags(cmd, "-f", "*.xml", "-fr", `^INV_.*\.xml$`); err == nil {
+		t.Logf("[fileFilter] filter and regular expression filter accepted together")
+		t.Fail()
+	}
+}
+
+func TestFileFilterExclude(t *testing.T) {
+	dir := t.TempDir()
+	for _, v := range []string{"a.csv", "a_tmp.csv", "b.txt", "staging/c.csv", "2024/d.csv", "2024/d_tmp.csv"} {
+		file := filepath.Join(dir, filepath.FromSlash(v))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(v), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	excludeFile := filepath.Join(t.TempDir(), "exclude.txt")
+	if err := os.WriteFile(excludeFile, []byte("# temporary files\n\n*_tmp.csv\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := flag.NewFlagSet("test", flag.ContinueOnError)
+	filter, err := parseFilterFlags(cmd, "-f", "*.csv", "-x", "staging/**", "-xf", excludeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := filter.files(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, v := range files {
+		names = append(names, relativeName(dir, v, true))
+	}
+	if strings.Join(names, ",") != "2024/d.csv,a.csv" {
+		t.Logf("[fileFilter] files => {%s}", strings.Join(names, ","))
+		t.Fail()
+	}
+}
+
+// cria o filtro a partir dos parametros informados
+func parseFilterFlags(cmd *flag.FlagSet, args ...string) (*fileFilter, error) {
+	p := addFilterFlags(cmd)
+	if err := cmd.Parse(args); err != nil {
+		return nil, err
+	}
+	return p.filter()
+}
+
+func TestParseAge(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+	in := map[string]time.Time{
+		"2h":                   now.Add(-2 * time.Hour),
+		"7d":                   now.AddDate(0, 0, -7),
+		"1d12h":                now.Add(-36 * time.Hour),
+		"2024-06-01":           time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local),
+		"2024-06-01T08:30:00":  time.Date(2024, 6, 1, 8, 30, 0, 0, time.Local),
+		"2024-06-01T08:30:00Z": time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC),
+	}
+	for k, v := range in {
+		n, err := parseAge(k, now)
+		if err != nil || !n.Equal(v) {
+			t.Logf("[parseAge] %s => {%s} != {%s}, %v", k, n, v, err)
+			t.Fail()
+		}
+	}
+	for _, v := range []string{"7x", "2024-13-01", "d", "99999999999999999999d", "200000d", "106751d24h", "-2h", "2d-5h"} {
+		if _, err := parseAge(v, now); err == nil {
+			t.Logf("[parseAge] invalid value {%s} accepted", v)
+			t.Fail()
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	in := map[string]int64{
+		"0":     0,
+		"1":     1,
+		"512B":  512,
+		"10KB":  10 * 1024,
+		"5mb":   5 * 1024 * 1024,
+		"2 GB":  2 * 1024 * 1024 * 1024,
+		"1T":    1024 * 1024 * 1024 * 1024,
+		"100KB": 100 * 1024,
+	}
+	for k, v := range in {
+		n, err := parseSize(k)
+		if err != nil || n != v {
+			t.Logf("[parseSize] %s => {%d} != {%d}, %v", k, n, v, err)
+			t.Fail()
+		}
+	}
+	for _, v := range []string{"", "-1", "10XB", "1.5GB", "99999999TB", "9223372036854775807K", "99999999999999999999"} {
+		if _, err := parseSize(v); err == nil {
+			t.Logf("[parseSize] invalid size {%s} accepted", v)
+			t.Fail()
+		}
+	}
+	cmd := flag.NewFlagSet("test", flag.ContinueOnError)
+	if _, err := parseFilterFlags(cmd, "-f", "*.csv", "-maxsize", "0"); err == nil {
+		t.Logf("[fileFilter] maximum size zero accepted")
+		t.Fail()
+	}
+	// o motivo da exclusão deve ser informado
+	filter := &fileFilter{MinSize: 1, MaxSize: 1024}
+	in2 := map[int64]string{
+		0:    "size 0 is smaller than minimum size 1",
+		1024: "",
+		2048: "size 2048 is larger than maximum size 1024",
+	}
+	for k, v := range in2 {
+		if reason := filter.exclusion("a.txt", time.Now(), k); reason != v {
+			t.Logf("[fileFilter] exclusion of size %d => {%s} != {%s}", k, reason, v)
+			t.Fail()
+		}
+	}
+}
+
+// cliente http que responde as requisições sem acessar o bucket
+type fakeHTTPClient func(r *http.Request) *http.Response
+
+func (p fakeHTTPClient) Do(r *http.Request) (*http.Response, error) {
+	return p(r), nil
+}
+
+func TestKeyExists(t *testing.T) {
+	savedConfig, savedClient := myConfig, s3client
+	t.Cleanup(func() { myConfig, s3client = savedConfig, savedClient })
+	myConfig = &Config{Bucket: "bucket"}
+	// a listagem retorna a primeira chave iniciada pelo prefixo
+	keys := map[string]string{"in/a.csv": "in/a.csv", "in/b.csv": "in/b.csv.bak"}
+	s3client = s3.New(s3.Options{
+		Region:           "us-east-1",
+		Credentials:      aws.AnonymousCredentials{},
+		EndpointResolver: s3.EndpointResolverFromURL("http://s3.test"),
+		UsePathStyle:     true,
+		HTTPClient: fakeHTTPClient(func(r *http.Request) *http.Response {
+			body := "<ListBucketResult>"
+			if key, ok := keys[r.URL.Query().Get("prefix")]; ok {
+				body += "<Contents><Key>" + key + "</Key><Size>10</Size></Contents>"
+			}
+			body += "</ListBucketResult>"
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), Request: r}
+		}),
+	})
+	in := map[string]bool{
+		"in/a.csv": true,
+		"in/b.csv": false,
+		"in/c.csv": false,
+	}
+	for k, v := range in {
+		exists, err := keyExists(k)
+		if err != nil || exists != v {
+			t.Logf("[keyExists] key {%s} => %v != %v, %v", k, exists, v, err)
+			t.Fail()
+		}
+	}
+}
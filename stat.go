@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// define as propriedades de um objeto exibidas em json
+type statEntry struct {
+	Key          string            `json:"key"`
+	Size         int64             `json:"size"`
+	LastModified *time.Time        `json:"last_modified,omitempty"`
+	ETag         string            `json:"etag,omitempty"`
+	ContentType  string            `json:"content_type,omitempty"`
+	StorageClass string            `json:"storage_class,omitempty"`
+	Encryption   string            `json:"encryption,omitempty"`
+	KMSKeyId     string            `json:"kms_key_id,omitempty"`
+	VersionId    string            `json:"version_id,omitempty"`
+	Expiration   string            `json:"expiration,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// processa o comando de exibição das propriedades dos arquivos
+func processStat(args []string) {
+	// identifica os flags informados
+	cmdStat := flag.NewFlagSet("stat", flag.ExitOnError)
+	// define os parametros para sobrescrever o padrão configurado
+	pBucketFlags := addBucketFlags(cmdStat)
+	// define os parametros para utilização específicos para este método
+	pFilter := cmdStat.String("f", "", "filter to select files, other file names can be informed after the flags")
+	pJSON := cmdStat.Bool("json", false, "show the properties of each object as a json line")
+	// processa os parametros
+	err := cmdStat.Parse(args)
+	if err != nil || len(args) == 0 {
+		cmdStat.Usage()
+		os.Exit(1)
+	}
+	// aplica os parametros de acesso ao bucket
+	pBucketFlags.apply()
+	// identifica os filtros informados
+	var filters []string
+	if *pFilter != "" {
+		filters = append(filters, *pFilter)
+	}
+	filters = append(filters, cmdStat.Args()...)
+	if len(filters) == 0 {
+		log.Fatalf("file name filter not provided")
+	}
+	// valida se há parametros suficientes
+	if myConfig.Bucket == "" {
+		cmdStat.Usage()
+		os.Exit(1)
+	}
+	// inicializa o serviço da aws
+	err = connect(*pBucketFlags.Role)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// exibe as propriedades
+	missing, err := statFiles(filters, *pBucketFlags.Prefix, *pJSON)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if missing > 0 {
+		os.Exit(1)
+	}
+}
+
+// exibe as propriedades dos arquivos que atendem aos filtros e retorna a
+// quantidade de arquivos que não foram encontrados
+func statFiles(filters []string, prefix string, jsonOutput bool) (missing int, err error) {
+	// ajusta os campos traduzindo as variaveis se utilizadas
+	prefix = parseName("", prefix)
+	enc := json.NewEncoder(os.Stdout)
+	for _, filter := range filters {
+		filter = parseName("", filter)
+		// seleciona os objetos do bucket que atendem ao filtro
+		matches, err := selectObjects(filter, prefix)
+		if err != nil {
+			return missing, err
+		}
+		if len(matches) == 0 {
+			log.Printf("no files found in bucket {%s} with filter {%s}", myConfig.Bucket, filter)
+			missing++
+			continue
+		}
+		for _, v := range matches {
+			entry, err := statObject(*v.Key)
+			if err != nil {
+				if isNotFound(err) {
+					log.Printf("file {%s} not found in bucket {%s}", *v.Key, myConfig.Bucket)
+					missing++
+					continue
+				}
+				return missing, fmt.Errorf("unable to read properties of file {%s}, %s", *v.Key, err)
+			}
+			if jsonOutput {
+				enc.Encode(entry)
+			} else {
+				printStat(os.Stdout, entry)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// lê as propriedades do objeto
+func statObject(key string) (*statEntry, error) {
+	head, err := s3client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(myConfig.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &statEntry{
+		Key:          key,
+		Size:         head.ContentLength,
+		LastModified: head.LastModified,
+		ETag:         strings.Trim(aws.ToString(head.ETag), `"`),
+		ContentType:  aws.ToString(head.ContentType),
+		StorageClass: string(head.StorageClass),
+		Encryption:   string(head.ServerSideEncryption),
+		KMSKeyId:     aws.ToString(head.SSEKMSKeyId),
+		VersionId:    aws.ToString(head.VersionId),
+		Expiration:   aws.ToString(head.Expiration),
+		Metadata:     head.Metadata,
+	}, nil
+}
+
+// exibe as propriedades do objeto em formato legível
+func printStat(w io.Writer, entry *statEntry) {
+	fmt.Fprintf(w, "%-15s %s\n", "key:", entry.Key)
+	fmt.Fprintf(w, "%-15s %d\n", "size:", entry.Size)
+	fmt.Fprintf(w, "%-15s %s\n", "last modified:", aws.ToTime(entry.LastModified).Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "%-15s %s\n", "etag:", entry.ETag)
+	fmt.Fprintf(w, "%-15s %s\n", "content type:", entry.ContentType)
+	// a classe padrão não é retornada pelo bucket
+	storageClass := entry.StorageClass
+	if storageClass == "" {
+		storageClass = "STANDARD"
+	}
+	fmt.Fprintf(w, "%-15s %s\n", "storage class:", storageClass)
+	if entry.Encryption != "" {
+		fmt.Fprintf(w, "%-15s %s\n", "encryption:", entry.Encryption)
+	}
+	if entry.KMSKeyId != "" {
+		fmt.Fprintf(w, "%-15s %s\n", "kms key id:", entry.KMSKeyId)
+	}
+	if entry.VersionId != "" {
+		fmt.Fprintf(w, "%-15s %s\n", "version id:", entry.VersionId)
+	}
+	if entry.Expiration != "" {
+		fmt.Fprintf(w, "%-15s %s\n", "expiration:", entry.Expiration)
+	}
+	if len(entry.Metadata) > 0 {
+		fmt.Fprintf(w, "%-15s\n", "metadata:")
+		keys := make([]string, 0, len(entry.Metadata))
+		for k := range entry.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "  %s=%s\n", k, entry.Metadata[k])
+		}
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// define o nome usado para ler da entrada padrão ou gravar na saída padrão
+const stdStream = "-"
+
+// contador de bytes gravados
+type countWriter struct {
+	n int64
+}
+
+// contabiliza os bytes gravados
+func (p *countWriter) Write(b []byte) (int, error) {
+	p.n += int64(len(b))
+	return len(b), nil
+}
+
+// Envia o conteúdo da entrada padrão para o bucket, como o tamanho não
+// é conhecido o envio é feito em partes e a memória usada é limitada ao
+// tamanho da parte vezes a quantidade de partes enviadas em paralelo
+func sendStream(prefix string, rename string, metaData map[string]string, tags map[string]string, verify string) error {
+	// loga o endpoint e o bucket que será usado
+	logEndpoint()
+	// define o nome do objeto no bucket
+	key := parseName("", prefix) + parseName("", rename)
+	log.Printf("[0] selected to upload: stdin => %s", key)
+	// define o tamanho da parte sem conhecer o tamanho do conteúdo
+	partSize := streamPartSize()
+	// calcula o tamanho, o ETag e o checksum enquanto o conteúdo é lido
+	counter := &countWriter{}
+	writers := []io.Writer{counter}
+	var h *contentHash
+	var err error
+	if verify != "" {
+		h, err = newContentHash(verify, partSize)
+		if err != nil {
+			return err
+		}
+		writers = append(writers, h)
+	}
+	// realiza o envio, o uploader lê as partes em sequência
+	start := time.Now()
+	log.Printf("[0] starting upload of stdin...")
+	uploader := manager.NewUploader(s3client)
+	input := &s3.PutObjectInput{
+		Bucket:   aws.String(myConfig.Bucket),
+		Key:      aws.String(key),
+		Body:     io.TeeReader(os.Stdin, io.MultiWriter(writers...)),
+		Metadata: metaData,
+	}
+	if tagging := objectTagging(tags, key); tagging != "" {
+		input.Tagging = aws.String(tagging)
+	}
+	_, err = uploader.Upload(context.TODO(), input, func(u *manager.Uploader) {
+		u.PartSize = partSize
+	})
+	if err != nil {
+		return fmt.Errorf("transfer failed, %s", err)
+	}
+	// verifica a integridade do objeto gravado e grava o checksum
+	if verify != "" {
+		err = verifyUpload(key, counter.n, h.Sum(), h.ETag(), verify)
+		if err == nil {
+			err = storeChecksum(key, metaData, verify, h.Sum())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	elapsed := time.Since(start).Seconds()
+	log.Printf("[0] upload completed, size: %d elapsed: %.2fs rate: %.2fMB/s", counter.n, elapsed, transferRate(counter.n, elapsed))
+	return nil
+}
+
+// define o tamanho da parte do envio da entrada padrão, como o tamanho
+// do conteúdo não é conhecido é usado o tamanho configurado ou o padrão
+// para arquivos pequenos, limitando o envio a 10000 partes deste tamanho
+func streamPartSize() int64 {
+	return partSizeFor(0)
+}
+
+// Recebe o objeto que atende ao filtro gravando o conteúdo na saída
+// padrão, o filtro deve selecionar um único objeto
+func receiveStream(filter *fileFilter, prefix string, remove bool, errornofiles bool, verify string) error {
+	// loga o endpoint e o bucket que será usado
+	logEndpoint()
+	// ajusta os campos traduzindo as variaveis se utilizadas
+	prefix = parseName("", prefix)
+	filter.Glob = parseName("", filter.Glob)
+	// seleciona os objetos do bucket que atendem ao filtro
+	matches, err := filter.objects(prefix)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		log.Printf("no files found in bucket {%s} with filter {%s}", myConfig.Bucket, filter)
+		if errornofiles {
+			os.Exit(1)
+		}
+		return nil
+	}
+	if len(matches) > 1 {
+		return fmt.Errorf("filter {%s} selected %d files, only one file can be written to stdout", filter, len(matches))
+	}
+	key := *matches[0].Key
+	log.Printf("[0] selected to download: %s => stdout", key)
+	// identifica o tamanho e o checksum do objeto para verificação
+	var head *s3.HeadObjectOutput
+	if verify != "" {
+		head, err = s3client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+			Bucket: aws.String(myConfig.Bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to read properties of object, %s", err)
+		}
+	}
+	// inicia o download
+	start := time.Now()
+	log.Printf("[0] starting download of file {%s}...", key)
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(myConfig.Bucket),
+		Key:    aws.String(key),
+	}
+	if head != nil {
+		input.IfMatch = head.ETag
+	}
+	output, err := s3client.GetObject(context.TODO(), input)
+	if err != nil {
+		return fmt.Errorf("unable to download file, %s", err)
+	}
+	defer output.Body.Close()
+	// grava o conteúdo calculando o ETag e o checksum
+	writers := []io.Writer{os.Stdout}
+	var h *contentHash
+	if head != nil {
+		h, err = newContentHash(verify, etagPartSize(strings.Trim(aws.ToString(head.ETag), `"`), head.ContentLength))
+		if err != nil {
+			return err
+		}
+		writers = append(writers, h)
+	}
+	n, err := io.Copy(io.MultiWriter(writers...), output.Body)
+	if err != nil {
+		return fmt.Errorf("unable to download file, %s", err)
+	}
+	// verifica a integridade do conteúdo recebido
+	if head != nil {
+		err = verifyDownload(key, n, head, h)
+		if err != nil {
+			return err
+		}
+	}
+	elapsed := time.Since(start).Seconds()
+	log.Printf("[0] download completed, size: %d elapsed: %.2fs rate: %.2fMB/s", n, elapsed, transferRate(n, elapsed))
+	// remove o arquivo do bucket somente após a recepção com sucesso
+	if remove {
+		_, err = s3client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+			Bucket: aws.String(myConfig.Bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to remove file {%s} from bucket, %s", key, err)
+		}
+		log.Printf("[0] file {%s} removed from bucket", key)
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Define as formas de comparação entre os arquivos locais e os objetos do bucket
+const (
+	CompareBySize     = "size"
+	CompareByMTime    = "mtime"
+	CompareByChecksum = "checksum"
+)
+
+// processa o comando de sincronização
+func processSync(args []string) {
+	// define o help do comando
+	help := "Usage:\n"
+	help += " s3 sync put -?\n"
+	help += " s3 sync get -?\n"
+	if len(args) == 0 {
+		fmt.Print(help)
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "put":
+		processSyncPut(args[1:])
+	case "get":
+		processSyncGet(args[1:])
+	default:
+		fmt.Print(help)
+		os.Exit(1)
+	}
+}
+
+// processa o comando de sincronização da pasta local para o bucket
+func processSyncPut(args []string) {
+	// identifica os flags informados
+	cmdSync := flag.NewFlagSet("put", flag.ExitOnError)
+	// define os parametros para sobrescrever o padrão configurado
+	pBucketFlags := addBucketFlags(cmdSync)
+	pMetaData := cmdSync.String("m", "", "metadata that will be stored in the file uploaded to the bucket (sintax key1=value1;key2=value2...)")
+	// define os parametros para utilização específicos para este método
+	pFilter := cmdSync.String("f", "*", "filter to select files")
+	pCompare := cmdSync.String("cmp", CompareBySize, "how to detect changed files (size, mtime, checksum)")
+	pDelete := cmdSync.Bool("delete", false, "remove objects from the bucket that no longer exist in the local folder")
+	pDryRun := cmdSync.Bool("dryrun", false, "only show the synchronization plan")
+	pWorkers := cmdSync.Int("j", 1, "number of files uploaded in parallel")
+	// processa os parametros
+	err := cmdSync.Parse(args)
+	if err != nil || len(args) == 0 {
+		cmdSync.Usage()
+		os.Exit(1)
+	}
+	// aplica os parametros de acesso ao bucket
+	pBucketFlags.apply()
+	// configura os metadados que serão gravados por padrão em todos os
+	// arquivos que forem enviados para o bucket
+	if *pMetaData != "" {
+		myConfig.Metadata, err = parseMetadata(*pMetaData)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	// valida o filtro
+	if *pFilter == "" {
+		log.Fatalf("file name filter not provided")
+	}
+	// valida a forma de comparação
+	*pCompare = strings.ToLower(*pCompare)
+	if *pCompare != CompareBySize && *pCompare != CompareByMTime && *pCompare != CompareByChecksum {
+		log.Fatalf("compare method {%s} is invalid", *pCompare)
+	}
+	// valida a quantidade de envios em paralelo
+	if *pWorkers < 1 {
+		log.Fatalf("number of parallel uploads {%d} is invalid", *pWorkers)
+	}
+	// valida se há parametros suficientes
+	if myConfig.Bucket == "" {
+		cmdSync.Usage()
+		os.Exit(1)
+	}
+	// inicializa o serviço da aws
+	err = connect(*pBucketFlags.Role)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// executa a sincronização
+	err = syncPut(*pFilter, *pBucketFlags.Prefix, myConfig.LocalFolder, *pCompare, *pDelete, *pDryRun, myConfig.Metadata, *pWorkers)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// processa o comando de sincronização do bucket para a pasta local
+func processSyncGet(args []string) {
+	// identifica os flags informados
+	cmdSync := flag.NewFlagSet("get", flag.ExitOnError)
+	// define os parametros para sobrescrever o padrão configurado
+	pBucketFlags := addBucketFlags(cmdSync)
+	// define os parametros para utilização específicos para este método
+	pFilter := cmdSync.String("f", "*", "filter to select files")
+	pCompare := cmdSync.String("cmp", CompareBySize, "how to detect changed files (size, mtime, checksum)")
+	pDelete := cmdSync.Bool("delete", false, "remove files from the local folder that no longer exist in the bucket")
+	pDryRun := cmdSync.Bool("dryrun", false, "only show the synchronization plan")
+	pWorkers := cmdSync.Int("j", 1, "number of files downloaded in parallel")
+	// processa os parametros
+	err := cmdSync.Parse(args)
+	if err != nil || len(args) == 0 {
+		cmdSync.Usage()
+		os.Exit(1)
+	}
+	// aplica os parametros de acesso ao bucket
+	pBucketFlags.apply()
+	// valida o filtro
+	if *pFilter == "" {
+		log.Fatalf("file name filter not provided")
+	}
+	// valida a forma de comparação
+	*pCompare = strings.ToLower(*pCompare)
+	if *pCompare != CompareBySize && *pCompare != CompareByMTime && *pCompare != CompareByChecksum {
+		log.Fatalf("compare method {%s} is invalid", *pCompare)
+	}
+	// valida a quantidade de recepções em paralelo
+	if *pWorkers < 1 {
+		log.Fatalf("number of parallel downloads {%d} is invalid", *pWorkers)
+	}
+	// valida se há parametros suficientes
+	if myConfig.Bucket == "" {
+		cmdSync.Usage()
+		os.Exit(1)
+	}
+	// inicializa o serviço da aws
+	err = connect(*pBucketFlags.Role)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// executa a sincronização
+	err = syncGet(*pFilter, *pBucketFlags.Prefix, myConfig.LocalFolder, *pCompare, *pDelete, *pDryRun, *pWorkers)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Sincroniza os arquivos da pasta local com o prefixo do bucket, enviando
+// apenas os arquivos novos ou alterados
+func syncPut(filter string, prefix string, folder string, compare string, remove bool, dryRun bool, metaData map[string]string, workers int) error {
+	// loga o endpoint e o bucket que será usado
+	logEndpoint()
+	// ajusta os campos traduzindo as variaveis se utilizadas
+	prefix = parseName("", prefix)
+	filter = parseName("", filter)
+	// lista os arquivos locais que batem com o filtro
+	files, err := listFiles(folder, filter, true)
+	if err != nil {
+		return fmt.Errorf("unable to list files with filter {%s}, %s", filter, err)
+	}
+	match, err := relativeMatcher(filter)
+	if err != nil {
+		return fmt.Errorf("unable filter files, %s", err)
+	}
+	// lista os objetos do bucket abaixo do prefixo
+	remote := make(map[string]types.Object)
+	count, err := listObjects(prefix, func(obj types.Object) error {
+		remote[*obj.Key] = obj
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("total of keys verified in bucket {%s}: %d", myConfig.Bucket, count)
+	// define os arquivos que devem ser enviados
+	var uploads []uploadItem
+	var unchanged int
+	local := make(map[string]bool)
+	for _, v := range files {
+		key := prefix + objectName(folder, v, "#FN#FE", true)
+		local[key] = true
+		reason := "new"
+		if obj, ok := remote[key]; ok {
+			changed, err := fileChanged(v, obj, compare, false)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				unchanged++
+				continue
+			}
+			reason = "changed"
+		}
+		log.Printf("[%d] plan to upload (%s): %s => %s", len(uploads), reason, v, key)
+		uploads = append(uploads, uploadItem{Path: v, Key: key})
+	}
+	// define os objetos que não existem mais na pasta local
+	var deletes []string
+	if remove {
+		for key := range remote {
+			if local[key] || strings.HasSuffix(key, "/") || !match(strings.TrimPrefix(key, prefix)) {
+				continue
+			}
+			deletes = append(deletes, key)
+		}
+		sort.Strings(deletes)
+		for k, v := range deletes {
+			log.Printf("[%d] plan to delete: %s", k, v)
+		}
+	}
+	// exibe o plano de sincronização
+	log.Printf("sync plan, local files: %d upload: %d unchanged: %d delete: %d", len(files), len(uploads), unchanged, len(deletes))
+	if dryRun {
+		return nil
+	}
+	// realiza o envio dos arquivos
+	if len(uploads) > 0 {
+		err = uploadFiles(uploads, false, metaData, workers, false, "")
+		if err != nil {
+			return fmt.Errorf("%s, remote objects were not deleted", err)
+		}
+	}
+	// remove os objetos que não existem mais na pasta local
+	return removeKeys(deletes)
+}
+
+// Sincroniza o prefixo do bucket com a pasta local, recebendo apenas os
+// objetos novos ou alterados
+func syncGet(filter string, prefix string, folder string, compare string, remove bool, dryRun bool, workers int) error {
+	// loga o endpoint e o bucket que será usado
+	logEndpoint()
+	// ajusta os campos traduzindo as variaveis se utilizadas
+	prefix = parseName("", prefix)
+	filter = parseName("", filter)
+	match, err := relativeMatcher(filter)
+	if err != nil {
+		return fmt.Errorf("unable filter files, %s", err)
+	}
+	// lista os objetos do bucket abaixo do prefixo que batem com o filtro
+	var downloads []downloadItem
+	var unchanged int
+	remote := make(map[string]bool)
+	count, err := listObjects(prefix, func(obj types.Object) error {
+		key := *obj.Key
+		if strings.HasSuffix(key, "/") || !match(strings.TrimPrefix(key, prefix)) {
+			return nil
+		}
+		remote[key] = true
+		// verifica se o arquivo local não existe ou esta desatualizado
+		filePath, err := localPath(folder, prefix, key, "#FN#FE", LayoutTree)
+		reason := "new"
+		if err == nil {
+			if _, err := os.Stat(filePath); err == nil {
+				changed, err := fileChanged(filePath, obj, compare, true)
+				if err != nil {
+					return err
+				}
+				if !changed {
+					unchanged++
+					return nil
+				}
+				reason = "changed"
+			}
+		}
+		log.Printf("[%d] plan to download (%s): %s => %s", len(downloads), reason, key, filePath)
+		downloads = append(downloads, downloadItem{Key: key, Path: filePath, Err: err})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("total of keys verified in bucket {%s}: %d", myConfig.Bucket, count)
+	// define os arquivos locais que não existem mais no bucket
+	var deletes []string
+	if remove {
+		files, err := listFiles(folder, filter, true)
+		if err != nil {
+			return fmt.Errorf("unable to list files with filter {%s}, %s", filter, err)
+		}
+		for _, v := range files {
+			if !remote[prefix+objectName(folder, v, "#FN#FE", true)] {
+				log.Printf("[%d] plan to delete: %s", len(deletes), v)
+				deletes = append(deletes, v)
+			}
+		}
+	}
+	// exibe o plano de sincronização
+	log.Printf("sync plan, remote keys: %d download: %d unchanged: %d delete: %d", len(remote), len(downloads), unchanged, len(deletes))
+	if dryRun {
+		return nil
+	}
+	// realiza a recepção dos arquivos
+	if len(downloads) > 0 {
+		err = downloadFiles(downloads, false, workers, false, "")
+		if err != nil {
+			return fmt.Errorf("%s, local files were not deleted", err)
+		}
+	}
+	// remove os arquivos que não existem mais no bucket
+	var failed int
+	for k, v := range deletes {
+		err = os.Remove(v)
+		if err != nil {
+			failed++
+			log.Printf("[%d] unable to remove file {%s}, %s", k, v, err)
+		} else {
+			log.Printf("[%d] file {%s} removed successfully", k, v)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to remove %d of %d files", failed, len(deletes))
+	}
+	return nil
+}
+
+// verifica se o arquivo local é diferente do objeto no bucket, na
+// comparação por data é verificado se a origem é mais recente que o
+// destino, sendo o objeto a origem quando for recepção
+func fileChanged(file string, obj types.Object, compare string, download bool) (bool, error) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return false, fmt.Errorf("unable to read properties of file {%s}, %s", file, err)
+	}
+	if stat.Size() != obj.Size {
+		return true, nil
+	}
+	switch compare {
+	case CompareByMTime:
+		if obj.LastModified == nil {
+			return false, nil
+		}
+		if download {
+			return obj.LastModified.After(stat.ModTime()), nil
+		}
+		return stat.ModTime().After(*obj.LastModified), nil
+	case CompareByChecksum:
+		remote := strings.Trim(aws.ToString(obj.ETag), `"`)
+		etag, err := fileETag(file, etagPartSize(remote, stat.Size()))
+		if err != nil {
+			return false, err
+		}
+		return etag != remote, nil
+	}
+	return false, nil
+}
+
+// identifica o tamanho da parte usado para gerar o ETag do objeto, pois
+// o objeto pode ter sido enviado com outro tamanho de parte
+func etagPartSize(etag string, size int64) int64 {
+	i := strings.LastIndex(etag, "-")
+	if i < 0 {
+		// envio em parte única
+		if size < 1 {
+			return 1
+		}
+		return size
+	}
+	var parts int64
+	if _, err := fmt.Sscanf(etag[i+1:], "%d", &parts); err != nil || parts < 1 {
+		return partSizeFor(size)
+	}
+	// verifica se o tamanho padrão gera a mesma quantidade de partes
+	partSize := partSizeFor(size)
+	if (size+partSize-1)/partSize == parts {
+		return partSize
+	}
+	// assume o tamanho da parte arredondado em MB
+	partSize = (size + parts - 1) / parts
+	return (partSize + 1024*1024 - 1) / (1024 * 1024) * 1024 * 1024
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Define as ações do comando de tags
+const (
+	TagGet = "get"
+	TagSet = "set"
+	TagRm  = "rm"
+)
+
+// define a quantidade máxima de tags de um objeto
+const maxTags = 10
+
+// define as tags de um objeto no formato json
+type tagEntry struct {
+	Key  string            `json:"key"`
+	Tags map[string]string `json:"tags"`
+}
+
+// processa o comando de gerenciamento das tags dos arquivos
+func processTag(args []string) {
+	// define o help do comando
+	help := "Usage:\n"
+	help += " s3 tag get -?\n"
+	help += " s3 tag set -?\n"
+	help += " s3 tag rm -?\n"
+	if len(args) == 0 {
+		fmt.Print(help)
+		os.Exit(1)
+	}
+	switch args[0] {
+	case TagGet, TagSet, TagRm:
+		processTagAction(args[0], args[1:])
+	default:
+		fmt.Print(help)
+		os.Exit(1)
+	}
+}
+
+// processa a ação informada nas tags dos arquivos
+func processTagAction(action string, args []string) {
+	// identifica os flags informados
+	cmdTag := flag.NewFlagSet("tag "+action, flag.ExitOnError)
+	// define os parametros para sobrescrever o padrão configurado
+	pBucketFlags := addBucketFlags(cmdTag)
+	// define os parametros para utilização específicos para este método
+	pFilter := cmdTag.String("f", "", "filter to select files")
+	pErrorNoFiles := cmdTag.Bool("enf", false, "terminate with exit code 1 if no files found")
+	var pTags *string
+	pReplace, pJSON := new(bool), new(bool)
+	switch action {
+	case TagGet:
+		pJSON = cmdTag.Bool("json", false, "show the tags of each object as a json line")
+	case TagSet:
+		pTags = cmdTag.String("t", "", fmt.Sprintf("tags that will be stored in the files, the values can use the variables of the file name (sintax key1=value1;key2=value2...)\n%s", renameVars))
+		pReplace = cmdTag.Bool("replace", false, "replace all tags of the files instead of adding to the current tags")
+	case TagRm:
+		pTags = cmdTag.String("t", "", "name of the tags that will be removed, all tags are removed if not provided (sintax key1;key2...)")
+	}
+	// processa os parametros
+	err := cmdTag.Parse(args)
+	if err != nil || len(args) == 0 {
+		cmdTag.Usage()
+		os.Exit(1)
+	}
+	// aplica os parametros de acesso ao bucket
+	pBucketFlags.apply()
+	// valida o filtro
+	if *pFilter == "" {
+		log.Fatalf("file name filter not provided")
+	}
+	// identifica as tags
+	var tags map[string]string
+	switch action {
+	case TagSet:
+		if *pTags == "" {
+			log.Fatalf("tags not provided")
+		}
+		tags, err = parseTags(*pTags)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case TagRm:
+		if *pTags != "" {
+			tags = make(map[string]string)
+			for _, v := range strings.Split(*pTags, ";") {
+				tags[strings.TrimSpace(v)] = ""
+			}
+		}
+	}
+	// valida se há parametros suficientes
+	if myConfig.Bucket == "" {
+		cmdTag.Usage()
+		os.Exit(1)
+	}
+	// inicializa o serviço da aws
+	err = connect(*pBucketFlags.Role)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// executa a ação
+	err = tagFiles(action, *pFilter, *pBucketFlags.Prefix, tags, *pReplace, *pJSON, *pErrorNoFiles)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// exibe, grava ou remove as tags dos arquivos que atendem ao filtro
+func tagFiles(action string, filter string, prefix string, tags map[string]string, replace bool, jsonOutput bool, errornofiles bool) error {
+	// loga o endpoint e o bucket que será usado
+	logEndpoint()
+	// ajusta os campos traduzindo as variaveis se utilizadas
+	prefix = parseName("", prefix)
+	filter = parseName("", filter)
+	// seleciona os objetos do bucket que atendem ao filtro
+	matches, err := selectObjects(filter, prefix)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		log.Printf("no files found in bucket {%s} with filter {%s}", myConfig.Bucket, filter)
+		if errornofiles {
+			os.Exit(1)
+		}
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	failed := 0
+	for k, v := range matches {
+		key := *v.Key
+		switch action {
+		case TagGet:
+			current, err := objectTags(key)
+			if err != nil {
+				failed++
+				log.Printf("[%d] failed to read tags of file {%s}, %s", k, key, err)
+				continue
+			}
+			if jsonOutput {
+				enc.Encode(tagEntry{Key: key, Tags: current})
+			} else {
+				fmt.Printf("%s %s\n", key, formatTags(current))
+			}
+		case TagSet:
+			err = setObjectTags(key, tags, replace)
+			if err != nil {
+				failed++
+				log.Printf("[%d] failed to set tags of file {%s}, %s", k, key, err)
+				continue
+			}
+			log.Printf("[%d] tags of file {%s} updated", k, key)
+		case TagRm:
+			err = removeObjectTags(key, tags)
+			if err != nil {
+				failed++
+				log.Printf("[%d] failed to remove tags of file {%s}, %s", k, key, err)
+				continue
+			}
+			log.Printf("[%d] tags of file {%s} removed", k, key)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to %s tags of %d of %d files", action, failed, len(matches))
+	}
+	return nil
+}
+
+// lê as tags do objeto
+func objectTags(key string) (map[string]string, error) {
+	output, err := s3client.GetObjectTagging(context.TODO(), &s3.GetObjectTaggingInput{
+		Bucket: aws.String(myConfig.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(output.TagSet))
+	for _, v := range output.TagSet {
+		tags[aws.ToString(v.Key)] = aws.ToString(v.Value)
+	}
+	return tags, nil
+}
+
+// grava as tags no objeto, os valores são traduzidos com as variaveis
+// do nome do arquivo e as tags atuais são mantidas se não for substituir
+func setObjectTags(key string, tags map[string]string, replace bool) error {
+	result := make(map[string]string)
+	if !replace {
+		current, err := objectTags(key)
+		if err != nil {
+			return err
+		}
+		result = current
+	}
+	for k, v := range tags {
+		result[k] = parseName(key, v)
+	}
+	return putObjectTags(key, result)
+}
+
+// remove as tags informadas do objeto, se não forem informadas remove
+// todas as tags
+func removeObjectTags(key string, tags map[string]string) error {
+	result := make(map[string]string)
+	if len(tags) > 0 {
+		current, err := objectTags(key)
+		if err != nil {
+			return err
+		}
+		for k, v := range current {
+			if _, ok := tags[k]; !ok {
+				result[k] = v
+			}
+		}
+	}
+	if len(result) > 0 {
+		return putObjectTags(key, result)
+	}
+	_, err := s3client.DeleteObjectTagging(context.TODO(), &s3.DeleteObjectTaggingInput{
+		Bucket: aws.String(myConfig.Bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// grava o conjunto de tags no objeto substituindo as atuais
+func putObjectTags(key string, tags map[string]string) error {
+	if len(tags) > maxTags {
+		return fmt.Errorf("object can have at most %d tags, %d provided", maxTags, len(tags))
+	}
+	tagSet := make([]types.Tag, 0, len(tags))
+	for k, v := range tags {
+		tagSet = append(tagSet, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	_, err := s3client.PutObjectTagging(context.TODO(), &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(myConfig.Bucket),
+		Key:     aws.String(key),
+		Tagging: &types.Tagging{TagSet: tagSet},
+	})
+	return err
+}
+
+// converte as tags no formato key1=value1;key2=value2...
+func parseTags(value string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for k, v := range strings.Split(value, ";") {
+		keyvalue := strings.SplitN(v, "=", 2)
+		if len(keyvalue) < 2 || strings.TrimSpace(keyvalue[0]) == "" {
+			return nil, fmt.Errorf("[%d] tag {%s} is invalid", k, v)
+		}
+		tags[strings.TrimSpace(keyvalue[0])] = strings.TrimSpace(keyvalue[1])
+	}
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("object can have at most %d tags, %d provided", maxTags, len(tags))
+	}
+	return tags, nil
+}
+
+// define as tags do objeto no formato de query string usado no envio,
+// os valores são traduzidos com as variaveis do nome do arquivo
+func objectTagging(tags map[string]string, name string) string {
+	values := make(map[string]string, len(tags))
+	for k, v := range tags {
+		values[k] = parseName(name, v)
+	}
+	return encodeTags(values)
+}
+
+// define as tags no formato de query string sem traduzir os valores
+func encodeTags(tags map[string]string) string {
+	values := url.Values{}
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// formata as tags no formato key1=value1;key2=value2...
+func formatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for k, v := range keys {
+		keys[k] = v + "=" + tags[v]
+	}
+	return strings.Join(keys, ";")
+}